@@ -0,0 +1,111 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+type AppAuth struct {
+	File   string   `toml:"file,omitempty"`
+	Realm  string   `toml:"realm,omitempty"`
+	Exempt []string `toml:"exempt,omitempty"`
+}
+
+func (a *AppAuth) Enabled() (enabled bool) {
+	enabled = a != nil && a.File != ""
+	return
+}
+
+func (a *AppAuth) Validate() (err error) {
+	if a == nil {
+		return
+	}
+	if a.File == "" {
+		err = fmt.Errorf("auth.file is required")
+		return
+	} else if !filepath.IsAbs(a.File) && strings.HasPrefix(filepath.Clean(a.File), "..") {
+		err = fmt.Errorf("auth.file must be absolute or relative to the auth.d directory: %q", a.File)
+		return
+	} else if strings.ContainsAny(a.Realm, "\"\r\n") {
+		err = fmt.Errorf("auth.realm must not contain quotes or newlines: %q", a.Realm)
+		return
+	}
+	for _, prefix := range a.Exempt {
+		if !strings.HasPrefix(prefix, "/") {
+			err = fmt.Errorf("auth.exempt paths must start with a slash: %q", prefix)
+			return
+		}
+	}
+	return
+}
+
+// GetFile returns the absolute path to the app's htpasswd file, relative
+// paths are within the auth.d directory
+func (a *AppAuth) GetFile(app *Application) (path string) {
+	if a == nil || a.File == "" {
+		return
+	} else if filepath.IsAbs(a.File) {
+		path = a.File
+	} else {
+		path = filepath.Join(app.Config.Paths.EtcAuth, a.File)
+	}
+	return
+}
+
+func (a *AppAuth) GetRealm() (realm string) {
+	if a != nil && a.Realm != "" {
+		realm = a.Realm
+	} else {
+		realm = DefaultAuthRealm
+	}
+	return
+}
+
+// IsExempt returns true if the cleaned request path is equal to, or within,
+// any of the exempt paths; requests with encoded dot-dot segments are never
+// exempt as the origin may decode them differently
+func (a *AppAuth) IsExempt(u *url.URL) (exempt bool) {
+	if a == nil || len(a.Exempt) == 0 || hasEncodedDotDot(u.RawPath) {
+		return
+	}
+	cleaned := cleanRequestPath(u.Path)
+	for _, prefix := range a.Exempt {
+		if cleaned == prefix || strings.HasPrefix(cleaned, strings.TrimSuffix(prefix, "/")+"/") {
+			exempt = true
+			return
+		}
+	}
+	return
+}
+
+// hasEncodedDotDot returns true if any segment of the encoded path decodes to
+// a dot-dot segment, including segments separated by encoded slashes
+func hasEncodedDotDot(rawPath string) (found bool) {
+	if !strings.Contains(rawPath, "%") {
+		return
+	}
+	separators := strings.NewReplacer("%2f", "/", "%2F", "/", "%5c", "/", "%5C", "/", "\\", "/")
+	for _, segment := range strings.Split(separators.Replace(rawPath), "/") {
+		if decoded, err := url.PathUnescape(segment); err != nil || decoded == ".." {
+			found = true
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestAppAuthIsExempt(t *testing.T) {
+	auth := &AppAuth{Exempt: []string{"/health", "/static/"}}
+
+	tests := []struct {
+		name   string
+		auth   *AppAuth
+		target string
+		expect bool
+	}{
+		{"nil auth", nil, "/health", false},
+		{"no exemptions", &AppAuth{}, "/health", false},
+		{"exact", auth, "/health", true},
+		{"within", auth, "/health/live", true},
+		{"within slash prefix", auth, "/static/app.css", true},
+		{"slash prefix exact", auth, "/static/", true},
+		{"slash prefix without slash", auth, "/static", false},
+		{"partial segment", auth, "/healthz", false},
+		{"other path", auth, "/admin", false},
+		{"dot segment", auth, "/./health", true},
+		{"repeated slashes", auth, "/health//live", true},
+		{"traversal out of exempt", auth, "/health/../admin", false},
+		{"traversal into exempt", auth, "/admin/../health", true},
+		{"traversal past root", auth, "/../../health", true},
+		{"encoded dot dot", auth, "/health/%2e%2e/admin", false},
+		{"encoded upper dot dot", auth, "/health/%2E%2E/admin", false},
+		{"encoded slash dot dot", auth, "/health%2f..%2fadmin", false},
+		{"encoded backslash dot dot", auth, "/health/..%5cadmin", false},
+		{"encoded dot", auth, "/health/%2e/live", true},
+		{"encoded name", auth, "/health/a%20b", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, err := url.Parse(test.target)
+			if err != nil {
+				t.Fatalf("url.Parse(%q) error: %v", test.target, err)
+			}
+			if got := test.auth.IsExempt(u); got != test.expect {
+				t.Errorf("IsExempt(%q) = %v, expected %v", test.target, got, test.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+)
+
+type AppCache struct {
+	Enable       bool  `toml:"enable,omitempty"`
+	MaxSize      int64 `toml:"max-size,omitempty"`
+	MemorySize   int64 `toml:"memory-size,omitempty"`
+	MaxEntrySize int64 `toml:"max-entry-size,omitempty"`
+}
+
+func (c *AppCache) Validate() (err error) {
+	if c == nil {
+		return
+	}
+	if c.MaxSize < 0 || c.MemorySize < 0 || c.MaxEntrySize < 0 {
+		err = fmt.Errorf("cache sizes must not be negative")
+	} else if c.GetMemorySize() > c.GetMaxSize() {
+		err = fmt.Errorf("cache.memory-size must not be larger than cache.max-size")
+	} else if c.GetMaxEntrySize() > c.GetMaxSize() {
+		err = fmt.Errorf("cache.max-entry-size must not be larger than cache.max-size")
+	}
+	return
+}
+
+func (c *AppCache) Enabled() (enabled bool) {
+	enabled = c != nil && c.Enable
+	return
+}
+
+// GetMaxSize returns the total number of body bytes cached for the app, both
+// in memory and on disk
+func (c *AppCache) GetMaxSize() (size int64) {
+	if c != nil && c.MaxSize > 0 {
+		size = c.MaxSize
+	} else {
+		size = DefaultCacheMaxSize
+	}
+	return
+}
+
+// GetMemorySize returns the number of body bytes held in memory before the
+// least recently used responses overflow to disk
+func (c *AppCache) GetMemorySize() (size int64) {
+	if c != nil && c.MemorySize > 0 {
+		size = c.MemorySize
+	} else {
+		size = DefaultCacheMemorySize
+	}
+	return
+}
+
+// GetMaxEntrySize returns the largest response body that will be cached
+func (c *AppCache) GetMaxEntrySize() (size int64) {
+	if c != nil && c.MaxEntrySize > 0 {
+		size = c.MaxEntrySize
+	} else {
+		size = DefaultCacheMaxEntrySize
+	}
+	return
+}
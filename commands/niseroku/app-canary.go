@@ -0,0 +1,124 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AppCanary struct {
+	Percent      int            `toml:"percent,omitempty"`
+	Cookie       string         `toml:"cookie,omitempty"`
+	Header       string         `toml:"header,omitempty"`
+	Duration     *time.Duration `toml:"duration,omitempty"`
+	MinRequests  int            `toml:"min-requests,omitempty"`
+	MaxErrorRate *float64       `toml:"max-error-rate,omitempty"`
+}
+
+// Enabled returns true if deployments of a next slug start with a canary
+// phase
+func (c *AppCanary) Enabled() (enabled bool) {
+	enabled = c != nil && (c.Percent > 0 || c.Cookie != "" || c.Header != "")
+	return
+}
+
+func (c *AppCanary) Validate() (err error) {
+	if c == nil {
+		return
+	}
+	switch {
+	case c.Percent < 0 || c.Percent > 100:
+		err = fmt.Errorf("canary.percent must be between 0 and 100")
+	case c.Cookie != "" && strings.HasPrefix(c.Cookie, "="):
+		err = fmt.Errorf("canary.cookie must be a name or name=value: %q", c.Cookie)
+	case c.Header != "" && strings.HasPrefix(c.Header, "="):
+		err = fmt.Errorf("canary.header must be a name or name=value: %q", c.Header)
+	case c.Duration != nil && *c.Duration < 0:
+		err = fmt.Errorf("canary.duration must not be negative")
+	case c.MinRequests < 0:
+		err = fmt.Errorf("canary.min-requests must not be negative")
+	case c.MaxErrorRate != nil && (*c.MaxErrorRate < 0 || *c.MaxErrorRate > 1):
+		err = fmt.Errorf("canary.max-error-rate must be between 0.0 and 1.0")
+	}
+	return
+}
+
+// GetDuration returns how long the canary runs before it is promoted
+func (c *AppCanary) GetDuration() (duration time.Duration) {
+	if c != nil && c.Duration != nil && *c.Duration > 0 {
+		duration = *c.Duration
+	} else {
+		duration = DefaultCanaryDuration
+	}
+	return
+}
+
+// GetMinRequests returns the number of canary requests required before the
+// error rates are compared
+func (c *AppCanary) GetMinRequests() (minRequests int) {
+	if c != nil && c.MinRequests > 0 {
+		minRequests = c.MinRequests
+	} else {
+		minRequests = DefaultCanaryMinRequests
+	}
+	return
+}
+
+// GetMaxErrorRate returns how much higher the canary 5xx rate may be than
+// the this-slug 5xx rate
+func (c *AppCanary) GetMaxErrorRate() (rate float64) {
+	if c != nil && c.MaxErrorRate != nil {
+		rate = *c.MaxErrorRate
+	} else {
+		rate = DefaultCanaryMaxErrorRate
+	}
+	return
+}
+
+// matchCanaryValue returns true if the named value is present, and equal to
+// the expected value if the setting is in the name=value form
+func matchCanaryValue(setting string, lookup func(name string) (value string, ok bool)) (matched bool) {
+	if setting == "" {
+		return
+	}
+	name, expected, hasValue := strings.Cut(setting, "=")
+	if value, ok := lookup(name); ok {
+		matched = !hasValue || value == expected
+	}
+	return
+}
+
+// Matches returns true if the request carries the canary cookie or header
+func (c *AppCanary) Matches(r *http.Request) (matched bool) {
+	if c == nil {
+		return
+	}
+	matched = matchCanaryValue(c.Cookie, func(name string) (value string, ok bool) {
+		if cookie, err := r.Cookie(name); err == nil {
+			value, ok = cookie.Value, true
+		}
+		return
+	}) || matchCanaryValue(c.Header, func(name string) (value string, ok bool) {
+		values := r.Header.Values(name)
+		if ok = len(values) > 0; ok {
+			value = values[0]
+		}
+		return
+	})
+	return
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type AppCertificate struct {
+	Domain   string `toml:"domain"`
+	CertFile string `toml:"cert-file"`
+	KeyFile  string `toml:"key-file"`
+}
+
+func (c *AppCertificate) Validate() (err error) {
+	switch {
+	case c.Domain == "":
+		err = fmt.Errorf("certificates.domain setting not found")
+	case strings.Contains(strings.TrimPrefix(c.Domain, "*."), "*"):
+		err = fmt.Errorf("certificates.domain wildcards must be the first label: %q", c.Domain)
+	case c.CertFile == "":
+		err = fmt.Errorf("certificates.cert-file setting not found: %v", c.Domain)
+	case c.KeyFile == "":
+		err = fmt.Errorf("certificates.key-file setting not found: %v", c.Domain)
+	}
+	return
+}
+
+// GetPaths returns the absolute cert-file and key-file paths, relative paths
+// are relative to the given secrets directory and all paths must be within
+// the secrets directory
+func (c *AppCertificate) GetPaths(secrets string) (certFile, keyFile string, err error) {
+	if certFile, err = resolveSecretsPath(secrets, c.CertFile); err != nil {
+		return
+	}
+	keyFile, err = resolveSecretsPath(secrets, c.KeyFile)
+	return
+}
+
+func resolveSecretsPath(secrets, name string) (resolved string, err error) {
+	if filepath.IsAbs(name) {
+		resolved = filepath.Clean(name)
+	} else {
+		resolved = filepath.Join(secrets, name)
+	}
+	if rel, ee := filepath.Rel(secrets, resolved); ee != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		err = fmt.Errorf("path is not within %v: %v", secrets, name)
+	}
+	return
+}
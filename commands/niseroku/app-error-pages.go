@@ -0,0 +1,95 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AppErrorPages struct {
+	Path       string            `toml:"path,omitempty"`
+	Files      map[string]string `toml:"files,omitempty"`
+	RetryAfter time.Duration     `toml:"retry-after,omitempty"`
+}
+
+func (e *AppErrorPages) Validate() (err error) {
+	if e == nil {
+		return
+	}
+	if e.Path != "" && (filepath.IsAbs(e.Path) || strings.HasPrefix(filepath.Clean(e.Path), "..")) {
+		err = fmt.Errorf("error-pages.path must be relative to the error-pages.d directory: %q", e.Path)
+		return
+	} else if e.RetryAfter < 0 {
+		err = fmt.Errorf("error-pages.retry-after must not be negative")
+		return
+	}
+	for key, file := range e.Files {
+		if status, ee := strconv.Atoi(key); ee != nil || status < 400 || status > 599 {
+			err = fmt.Errorf("error-pages.files keys must be 4xx or 5xx status codes: %q", key)
+			return
+		} else if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+			err = fmt.Errorf("error-pages.files must be relative to the error-pages path: %q", file)
+			return
+		}
+	}
+	return
+}
+
+// GetDirectory returns the absolute path to the app's error pages, which
+// defaults to the app name within the error-pages.d directory
+func (e *AppErrorPages) GetDirectory(app *Application) (dir string) {
+	name := app.Name
+	if e != nil && e.Path != "" {
+		name = e.Path
+	}
+	dir = filepath.Join(app.Config.Paths.EtcErrorPages, name)
+	return
+}
+
+// GetFiles returns the error page file names by status code, files named with
+// only the status code and a .html extension are used unless overridden by
+// the files setting
+func (e *AppErrorPages) GetFiles(found []string) (files map[int]string) {
+	files = make(map[int]string)
+	for _, name := range found {
+		if base := strings.TrimSuffix(name, ".html"); base != name {
+			if status, err := strconv.Atoi(base); err == nil && status >= 400 && status <= 599 {
+				files[status] = name
+			}
+		}
+	}
+	if e != nil {
+		for key, file := range e.Files {
+			if status, err := strconv.Atoi(key); err == nil {
+				files[status] = file
+			}
+		}
+	}
+	return
+}
+
+// GetRetryAfter returns the Retry-After duration for 503 responses
+func (e *AppErrorPages) GetRetryAfter() (retryAfter time.Duration) {
+	if e != nil && e.RetryAfter > 0 {
+		retryAfter = e.RetryAfter
+	} else {
+		retryAfter = DefaultErrorPageRetryAfter
+	}
+	return
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AppHealthCheck struct {
+	Path               string         `toml:"path,omitempty"`
+	Interval           *time.Duration `toml:"interval,omitempty"`
+	Timeout            *time.Duration `toml:"timeout,omitempty"`
+	HealthyThreshold   int            `toml:"healthy-threshold,omitempty"`
+	UnhealthyThreshold int            `toml:"unhealthy-threshold,omitempty"`
+}
+
+func (h *AppHealthCheck) Enabled() (enabled bool) {
+	enabled = h != nil && h.Path != ""
+	return
+}
+
+func (h *AppHealthCheck) Validate() (err error) {
+	if h == nil {
+		return
+	}
+	switch {
+	case h.Path != "" && !strings.HasPrefix(h.Path, "/"):
+		err = fmt.Errorf("health-check.path must start with a slash: %q", h.Path)
+	case h.HealthyThreshold < 0:
+		err = fmt.Errorf("health-check.healthy-threshold must not be negative")
+	case h.UnhealthyThreshold < 0:
+		err = fmt.Errorf("health-check.unhealthy-threshold must not be negative")
+	}
+	return
+}
+
+func (h *AppHealthCheck) GetInterval() (interval time.Duration) {
+	if h != nil && h.Interval != nil && *h.Interval > 0 {
+		interval = *h.Interval
+	} else {
+		interval = DefaultHealthCheckInterval
+	}
+	return
+}
+
+func (h *AppHealthCheck) GetTimeout() (timeout time.Duration) {
+	if h != nil && h.Timeout != nil && *h.Timeout > 0 {
+		timeout = *h.Timeout
+	} else {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return
+}
+
+func (h *AppHealthCheck) GetHealthyThreshold() (threshold int) {
+	if h != nil && h.HealthyThreshold > 0 {
+		threshold = h.HealthyThreshold
+	} else {
+		threshold = DefaultHealthCheckHealthyThreshold
+	}
+	return
+}
+
+func (h *AppHealthCheck) GetUnhealthyThreshold() (threshold int) {
+	if h != nil && h.UnhealthyThreshold > 0 {
+		threshold = h.UnhealthyThreshold
+	} else {
+		threshold = DefaultHealthCheckUnhealthyThreshold
+	}
+	return
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+)
+
+type AppMirror struct {
+	Slug          string   `toml:"slug,omitempty"`
+	Rate          float64  `toml:"rate,omitempty"`
+	Methods       []string `toml:"methods,omitempty"`
+	MaxConcurrent int      `toml:"max-concurrent,omitempty"`
+	MaxBodySize   int64    `toml:"max-body-size,omitempty"`
+}
+
+// Enabled returns true if a sample of requests is copied to a shadow slug
+func (m *AppMirror) Enabled() (enabled bool) {
+	enabled = m != nil && m.Rate > 0
+	return
+}
+
+func (m *AppMirror) Validate() (err error) {
+	if m == nil {
+		return
+	}
+	switch {
+	case m.Rate < 0 || m.Rate > 1:
+		err = fmt.Errorf("mirror.rate must be between 0.0 and 1.0")
+	case m.MaxConcurrent < 0:
+		err = fmt.Errorf("mirror.max-concurrent must not be negative")
+	case m.MaxBodySize < 0:
+		err = fmt.Errorf("mirror.max-body-size must not be negative")
+	}
+	return
+}
+
+// GetMaxConcurrent returns the number of mirrored requests allowed in flight
+func (m *AppMirror) GetMaxConcurrent() (maxConcurrent int) {
+	if m != nil && m.MaxConcurrent > 0 {
+		maxConcurrent = m.MaxConcurrent
+	} else {
+		maxConcurrent = DefaultMirrorMaxConcurrent
+	}
+	return
+}
+
+// GetMaxBodySize returns the largest request body which is buffered so that
+// it can be sent to both the primary and the shadow slug
+func (m *AppMirror) GetMaxBodySize() (size int64) {
+	if m != nil && m.MaxBodySize > 0 {
+		size = m.MaxBodySize
+	} else {
+		size = DefaultMirrorMaxBodySize
+	}
+	return
+}
+
+// GetMethods returns the request methods which are mirrored, only the safe
+// methods unless others are listed explicitly
+func (m *AppMirror) GetMethods() (methods []string) {
+	if m != nil && len(m.Methods) > 0 {
+		methods = m.Methods
+	} else {
+		methods = DefaultMirrorMethods
+	}
+	return
+}
+
+// Samples returns true if the request is selected to be mirrored
+func (m *AppMirror) Samples(r *http.Request) (sampled bool) {
+	if !m.Enabled() || IsUpgradeRequest(r) {
+		return
+	}
+	var allowed bool
+	for _, method := range m.GetMethods() {
+		if allowed = strings.EqualFold(method, r.Method); allowed {
+			break
+		}
+	}
+	if !allowed {
+		return
+	}
+	sampled = m.Rate >= 1 || rand.Float64() < m.Rate
+	return
+}
+
+// GetShadowSlug returns the slug which receives the mirrored requests, the
+// next slug unless the slug setting names another slug of the app
+func (m *AppMirror) GetShadowSlug(app *Application) (slug *Slug) {
+	if m == nil {
+		return
+	} else if m.Slug == "" || m.Slug == "next" {
+		slug = app.GetNextSlug()
+		return
+	}
+	app.RLock()
+	defer app.RUnlock()
+	for _, found := range app.Slugs {
+		if found.Name == m.Slug || found.Commit == m.Slug {
+			slug = found
+			return
+		}
+	}
+	return
+}
@@ -19,9 +19,14 @@ import (
 	"net"
 )
 
+// MaxUnixSocketPathLength is the longest unix socket path supported by both
+// linux and the BSDs, not counting the terminating NUL
+const MaxUnixSocketPathLength = 103
+
 type AppOrigin struct {
 	Scheme string `toml:"scheme,omitempty"`
 	Host   string `toml:"host,omitempty"`
+	Socket bool   `toml:"socket,omitempty"`
 }
 
 func (o AppOrigin) String() (baseUrl string) {
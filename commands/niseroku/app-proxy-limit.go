@@ -0,0 +1,73 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"math"
+)
+
+func (a *Application) validateProxyLimit() (err error) {
+	if a.ProxyLimit == nil {
+		return
+	}
+	switch {
+	case a.ProxyLimit.TTL < 0:
+		err = fmt.Errorf("proxy-limit.ttl must not be negative")
+	case a.ProxyLimit.Max < 0:
+		err = fmt.Errorf("proxy-limit.max must not be negative")
+	case a.ProxyLimit.Burst < 0:
+		err = fmt.Errorf("proxy-limit.burst must not be negative")
+	case a.ProxyLimit.MaxDelay < 0:
+		err = fmt.Errorf("proxy-limit.max-delay must not be negative")
+	case a.ProxyLimit.DelayScale < 0:
+		err = fmt.Errorf("proxy-limit.delay-scale must not be negative")
+	}
+	return
+}
+
+// GetProxyLimit returns the effective rate limits for this application, any
+// numeric settings not present in the app's [proxy-limit] table are inherited
+// from the niseroku.toml [proxy-limit] settings while the log flags are taken
+// as-is from the app's table
+func (a *Application) GetProxyLimit() (limits RateLimit, custom bool) {
+	limits = a.Config.ProxyLimit
+	if custom = a.ProxyLimit != nil; !custom {
+		return
+	}
+	override := a.ProxyLimit
+	if override.TTL > 0 {
+		limits.TTL = override.TTL
+	}
+	if override.Max > 0 {
+		limits.Max = override.Max
+		if override.Burst <= 0 {
+			limits.Burst = int(math.Max(1, override.Max))
+		}
+	}
+	if override.Burst > 0 {
+		limits.Burst = override.Burst
+	}
+	if override.MaxDelay > 0 {
+		limits.MaxDelay = override.MaxDelay
+	}
+	if override.DelayScale > 0 {
+		limits.DelayScale = override.DelayScale
+	}
+	limits.LogAllowed = override.LogAllowed
+	limits.LogDelayed = override.LogDelayed
+	limits.LogLimited = override.LogLimited
+	return
+}
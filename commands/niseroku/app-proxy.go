@@ -0,0 +1,112 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	BalanceRoundRobin = "round-robin"
+	BalanceLeastConn  = "least-conn"
+	BalanceRandomTwo  = "random-two"
+)
+
+// HstsPreloadMinMaxAge is the minimum hsts-max-age accepted by the HSTS
+// preload list
+const HstsPreloadMinMaxAge = 365 * 24 * time.Hour
+
+type AppProxy struct {
+	Balance string `toml:"balance,omitempty"`
+
+	StripPrefix bool `toml:"strip-prefix,omitempty"`
+
+	MaxBodySize int64 `toml:"max-body-size,omitempty"`
+
+	RetryBudget *AppRetryBudget `toml:"retry-budget,omitempty"`
+
+	ForceHttps            *bool         `toml:"force-https,omitempty"`
+	HstsMaxAge            time.Duration `toml:"hsts-max-age,omitempty"`
+	HstsIncludeSubdomains bool          `toml:"hsts-include-subdomains,omitempty"`
+	HstsPreload           bool          `toml:"hsts-preload,omitempty"`
+}
+
+func (p AppProxy) Validate() (err error) {
+	switch p.Balance {
+	case "", BalanceRoundRobin, BalanceLeastConn, BalanceRandomTwo:
+	default:
+		err = fmt.Errorf("invalid proxy.balance setting: %q", p.Balance)
+		return
+	}
+	switch {
+	case p.MaxBodySize < -1:
+		err = fmt.Errorf("proxy.max-body-size must be -1, zero or a positive number of bytes")
+	case p.HstsMaxAge < 0:
+		err = fmt.Errorf("proxy.hsts-max-age must not be negative")
+	case p.HstsMaxAge > 0 && !p.GetForceHttps():
+		err = fmt.Errorf("proxy.hsts-max-age requires proxy.force-https")
+	case p.HstsPreload && (!p.HstsIncludeSubdomains || p.HstsMaxAge < HstsPreloadMinMaxAge):
+		err = fmt.Errorf("proxy.hsts-preload requires proxy.hsts-include-subdomains and a proxy.hsts-max-age of at least %v", HstsPreloadMinMaxAge)
+	default:
+		err = p.RetryBudget.Validate()
+	}
+	return
+}
+
+// GetForceHttps returns true unless force-https is explicitly false, plain
+// http requests are redirected to https when enable-ssl is true
+func (p AppProxy) GetForceHttps() (force bool) {
+	force = p.ForceHttps == nil || *p.ForceHttps
+	return
+}
+
+// GetHstsHeader returns the Strict-Transport-Security header value, or an
+// empty string if hsts-max-age is not set
+func (p AppProxy) GetHstsHeader() (value string) {
+	if p.HstsMaxAge <= 0 {
+		return
+	}
+	value = "max-age=" + strconv.FormatInt(int64(p.HstsMaxAge.Seconds()), 10)
+	if p.HstsIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if p.HstsPreload {
+		value += "; preload"
+	}
+	return
+}
+
+// GetMaxBodySize returns the request body size limit, zero inherits the given
+// niseroku.toml [server] max-body-size and -1 is no limit
+func (p AppProxy) GetMaxBodySize(global int64) (limit int64) {
+	switch {
+	case p.MaxBodySize < 0:
+		limit = 0
+	case p.MaxBodySize > 0:
+		limit = p.MaxBodySize
+	default:
+		limit = global
+	}
+	return
+}
+
+func (p AppProxy) GetBalance() (balance string) {
+	if balance = p.Balance; balance == "" {
+		balance = BalanceRoundRobin
+	}
+	return
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type AppRedirect struct {
+	FromHost string `toml:"from-host"`
+	ToHost   string `toml:"to-host"`
+	Status   int    `toml:"status,omitempty"`
+	KeepPath *bool  `toml:"keep-path,omitempty"`
+}
+
+func (r *AppRedirect) Validate() (err error) {
+	switch {
+	case r.FromHost == "":
+		err = fmt.Errorf("redirects.from-host setting not found")
+	case r.ToHost == "":
+		err = fmt.Errorf("redirects.to-host setting not found: %v", r.FromHost)
+	case r.FromHost == r.ToHost:
+		err = fmt.Errorf("redirects.to-host is the same as from-host: %v", r.FromHost)
+	}
+	if err != nil {
+		return
+	}
+	switch r.Status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		err = fmt.Errorf("redirects.status is not a redirect status code: %v - %d", r.FromHost, r.Status)
+	}
+	return
+}
+
+func (r *AppRedirect) GetStatus() (status int) {
+	if status = r.Status; status == 0 {
+		status = http.StatusMovedPermanently
+	}
+	return
+}
+
+func (r *AppRedirect) GetKeepPath() (keep bool) {
+	keep = r.KeepPath == nil || *r.KeepPath
+	return
+}
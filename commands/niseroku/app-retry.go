@@ -0,0 +1,115 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"time"
+)
+
+type AppRetry struct {
+	Attempts        int            `toml:"attempts,omitempty"`
+	BreakerFailures int            `toml:"breaker-failures,omitempty"`
+	BreakerCooldown *time.Duration `toml:"breaker-cooldown,omitempty"`
+}
+
+func (r *AppRetry) Validate() (err error) {
+	if r == nil {
+		return
+	}
+	switch {
+	case r.Attempts < 0:
+		err = fmt.Errorf("retry.attempts must not be negative")
+	case r.BreakerFailures < 0:
+		err = fmt.Errorf("retry.breaker-failures must not be negative")
+	case r.BreakerCooldown != nil && *r.BreakerCooldown < 0:
+		err = fmt.Errorf("retry.breaker-cooldown must not be negative")
+	}
+	return
+}
+
+// GetAttempts returns the maximum number of origin requests made for one
+// client request, including the first
+func (r *AppRetry) GetAttempts() (attempts int) {
+	if r != nil && r.Attempts > 0 {
+		attempts = r.Attempts
+	} else {
+		attempts = DefaultRetryAttempts
+	}
+	return
+}
+
+// GetBreakerFailures returns the number of consecutive failures which open
+// the circuit breaker of a worker
+func (r *AppRetry) GetBreakerFailures() (failures int) {
+	if r != nil && r.BreakerFailures > 0 {
+		failures = r.BreakerFailures
+	} else {
+		failures = DefaultBreakerFailures
+	}
+	return
+}
+
+// GetBreakerCooldown returns how long an open circuit breaker keeps new
+// requests away from the worker
+func (r *AppRetry) GetBreakerCooldown() (cooldown time.Duration) {
+	if r != nil && r.BreakerCooldown != nil && *r.BreakerCooldown > 0 {
+		cooldown = *r.BreakerCooldown
+	} else {
+		cooldown = DefaultBreakerCooldown
+	}
+	return
+}
+
+// AppRetryBudget limits the retries of an app to a ratio of its requests, the
+// minimum is the number of retries allowed before the ratio takes effect and
+// the most the budget can hold
+type AppRetryBudget struct {
+	Ratio   *float64 `toml:"ratio,omitempty"`
+	Minimum *float64 `toml:"minimum,omitempty"`
+}
+
+func (b *AppRetryBudget) Validate() (err error) {
+	if b == nil {
+		return
+	}
+	switch {
+	case b.Ratio != nil && (*b.Ratio < 0 || *b.Ratio > 1):
+		err = fmt.Errorf("proxy.retry-budget.ratio must be between 0.0 and 1.0")
+	case b.Minimum != nil && *b.Minimum < 1:
+		err = fmt.Errorf("proxy.retry-budget.minimum must be at least 1, set retry.attempts to 1 to disable retries")
+	}
+	return
+}
+
+// GetRatio returns the retries allowed per request
+func (b *AppRetryBudget) GetRatio() (ratio float64) {
+	if b != nil && b.Ratio != nil {
+		ratio = *b.Ratio
+	} else {
+		ratio = DefaultRetryBudget
+	}
+	return
+}
+
+// GetMinimum returns the retries allowed regardless of the ratio
+func (b *AppRetryBudget) GetMinimum() (minimum float64) {
+	if b != nil && b.Minimum != nil {
+		minimum = *b.Minimum
+	} else {
+		minimum = DefaultRetryBudgetMinRetries
+	}
+	return
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"testing"
+)
+
+func TestAppRetryAttempts(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   *AppRetry
+		expect  int
+		invalid bool
+	}{
+		{"nil", nil, DefaultRetryAttempts, false},
+		{"unset", &AppRetry{}, DefaultRetryAttempts, false},
+		{"single", &AppRetry{Attempts: 1}, 1, false},
+		{"several", &AppRetry{Attempts: 5}, 5, false},
+		{"negative", &AppRetry{Attempts: -1}, DefaultRetryAttempts, true},
+		{"negative breaker failures", &AppRetry{Attempts: 2, BreakerFailures: -1}, 2, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.retry.Validate(); (err != nil) != test.invalid {
+				t.Errorf("Validate() error = %v, expected invalid %v", err, test.invalid)
+			}
+			if got := test.retry.GetAttempts(); got != test.expect {
+				t.Errorf("GetAttempts() = %d, expected %d", got, test.expect)
+			}
+		})
+	}
+}
+
+func TestAppRetryBudget(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		budget  *AppRetryBudget
+		ratio   float64
+		minimum float64
+		invalid bool
+	}{
+		{"nil", nil, DefaultRetryBudget, DefaultRetryBudgetMinRetries, false},
+		{"unset", &AppRetryBudget{}, DefaultRetryBudget, DefaultRetryBudgetMinRetries, false},
+		{"ratio only", &AppRetryBudget{Ratio: value(0.5)}, 0.5, DefaultRetryBudgetMinRetries, false},
+		{"minimum only", &AppRetryBudget{Minimum: value(3)}, DefaultRetryBudget, 3, false},
+		{"both", &AppRetryBudget{Ratio: value(0.1), Minimum: value(1)}, 0.1, 1, false},
+		{"zero ratio", &AppRetryBudget{Ratio: value(0)}, 0, DefaultRetryBudgetMinRetries, false},
+		{"whole ratio", &AppRetryBudget{Ratio: value(1)}, 1, DefaultRetryBudgetMinRetries, false},
+		{"negative ratio", &AppRetryBudget{Ratio: value(-0.1)}, -0.1, DefaultRetryBudgetMinRetries, true},
+		{"ratio above one", &AppRetryBudget{Ratio: value(1.5)}, 1.5, DefaultRetryBudgetMinRetries, true},
+		{"zero minimum", &AppRetryBudget{Minimum: value(0)}, DefaultRetryBudget, 0, true},
+		{"fractional minimum", &AppRetryBudget{Minimum: value(0.5)}, DefaultRetryBudget, 0.5, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.budget.Validate(); (err != nil) != test.invalid {
+				t.Errorf("Validate() error = %v, expected invalid %v", err, test.invalid)
+			}
+			if err := (AppProxy{RetryBudget: test.budget}).Validate(); (err != nil) != test.invalid {
+				t.Errorf("AppProxy.Validate() error = %v, expected invalid %v", err, test.invalid)
+			}
+			if got := test.budget.GetRatio(); got != test.ratio {
+				t.Errorf("GetRatio() = %v, expected %v", got, test.ratio)
+			}
+			if got := test.budget.GetMinimum(); got != test.minimum {
+				t.Errorf("GetMinimum() = %v, expected %v", got, test.minimum)
+			}
+		})
+	}
+}
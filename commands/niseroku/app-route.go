@@ -0,0 +1,175 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// AppRoute is a parsed Application.Domains entry, either a plain host name or
+// a host name with a path prefix, ie: "example.com/docs"
+type AppRoute struct {
+	Host   string
+	Prefix string
+	App    *Application
+}
+
+func NewAppRoute(domain string, app *Application) (route *AppRoute, err error) {
+	route = &AppRoute{App: app}
+	if idx := strings.Index(domain, "/"); idx >= 0 {
+		route.Host = domain[:idx]
+		route.Prefix = "/" + strings.Trim(domain[idx:], "/")
+		if route.Prefix == "/" {
+			route.Prefix = ""
+		}
+	} else {
+		route.Host = domain
+	}
+	route.Host = strings.ToLower(route.Host)
+	switch {
+	case route.Host == "":
+		err = fmt.Errorf("domain host not found: %q", domain)
+	case strings.ContainsAny(route.Prefix, "?#*"):
+		err = fmt.Errorf("domain path prefix must be a plain path: %q", domain)
+	case strings.Contains(route.Prefix, "//"):
+		err = fmt.Errorf("domain path prefix contains empty segments: %q", domain)
+	}
+	return
+}
+
+func (r *AppRoute) String() (route string) {
+	route = r.Host + r.Prefix
+	return
+}
+
+// Matches returns true if the path is the route prefix or is within it,
+// prefixes only match whole path segments
+func (r *AppRoute) Matches(path string) (matched bool) {
+	matched = r.Prefix == "" || path == r.Prefix || strings.HasPrefix(path, r.Prefix+"/")
+	return
+}
+
+// Contains returns true if the other route is mounted within this route
+func (r *AppRoute) Contains(other *AppRoute) (contains bool) {
+	contains = r.Host == other.Host && r.Prefix != other.Prefix && r.Matches(other.Prefix)
+	return
+}
+
+// StripPrefix removes the route prefix from the cleaned request path and adds
+// the X-Forwarded-Prefix header; the path is only cleaned if it is not within
+// the route prefix once cleaned
+func (r *AppRoute) StripPrefix(req *http.Request) {
+	if r.Prefix == "" {
+		return
+	}
+	cleaned := cleanRequestPath(req.URL.Path)
+	if !r.Matches(cleaned) {
+		req.URL.Path, req.URL.RawPath = cleaned, ""
+		return
+	}
+	if req.URL.Path = strings.TrimPrefix(cleaned, r.Prefix); req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if req.URL.RawPath != "" {
+		// an encoded path which no longer matches the cleaned path is ignored
+		// by url.URL.EscapedPath
+		if req.URL.RawPath = strings.TrimPrefix(cleanRequestPath(req.URL.RawPath), r.Prefix); req.URL.RawPath == "" {
+			req.URL.RawPath = "/"
+		}
+	}
+	req.Header.Set("X-Forwarded-Prefix", r.Prefix)
+}
+
+// cleanRequestPath returns the request path without dot segments or repeated
+// slashes, keeping any trailing slash
+func cleanRequestPath(urlPath string) (cleaned string) {
+	if urlPath == "" {
+		cleaned = "/"
+		return
+	}
+	cleaned = path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return
+}
+
+// sortAppRoutes orders the routes longest prefix first
+func sortAppRoutes(routes []*AppRoute) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Prefix) > len(routes[j].Prefix)
+	})
+}
+
+// findAppRoute returns the longest prefix route matching the cleaned path
+func findAppRoute(routes []*AppRoute, urlPath string) (route *AppRoute) {
+	cleaned := cleanRequestPath(urlPath)
+	for _, r := range routes {
+		if r.Matches(cleaned) {
+			route = r
+			return
+		}
+	}
+	return
+}
+
+// GetDomainHosts returns the unique host names of this application's domains
+func (a *Application) GetDomainHosts() (hosts []string) {
+	seen := make(map[string]struct{})
+	for _, domain := range a.Domains {
+		if route, err := NewAppRoute(domain, a); err == nil {
+			if _, exists := seen[route.Host]; !exists {
+				seen[route.Host] = struct{}{}
+				hosts = append(hosts, route.Host)
+			}
+		}
+	}
+	return
+}
+
+// FindNestedRoutes returns a description of each route mounted within the
+// route of another application; routes mounted within another application's
+// root route are nested, routes mounted within another application's path
+// prefix take part of that application's paths away and are conflicts
+func (c *Config) FindNestedRoutes() (nested, conflicts []string) {
+	c.RLock()
+	defer c.RUnlock()
+	var hosts []string
+	for host := range c.RouteLookup {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		routes := c.RouteLookup[host]
+		for _, outer := range routes {
+			for _, inner := range routes {
+				if outer.App == inner.App || !outer.Contains(inner) {
+					continue
+				}
+				description := fmt.Sprintf("%v (%v) is mounted within %v (%v)", inner, inner.App.Name, outer, outer.App.Name)
+				if outer.Prefix == "" {
+					nested = append(nested, description)
+				} else {
+					conflicts = append(conflicts, description)
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFindAppRoute(t *testing.T) {
+	root := &Application{Name: "root"}
+	docs := &Application{Name: "docs"}
+	api := &Application{Name: "api"}
+	var routes []*AppRoute
+	for _, entry := range []struct {
+		domain string
+		app    *Application
+	}{
+		{"example.com", root},
+		{"example.com/docs", docs},
+		{"example.com/docs/api/", api},
+	} {
+		if route, err := NewAppRoute(entry.domain, entry.app); err != nil {
+			t.Fatalf("NewAppRoute(%q) error: %v", entry.domain, err)
+		} else {
+			routes = append(routes, route)
+		}
+	}
+	sortAppRoutes(routes)
+
+	tests := []struct {
+		name   string
+		routes []*AppRoute
+		path   string
+		expect *Application
+	}{
+		{"root path", routes, "/", root},
+		{"root page", routes, "/about", root},
+		{"prefix exact", routes, "/docs", docs},
+		{"prefix slash", routes, "/docs/", docs},
+		{"prefix page", routes, "/docs/intro", docs},
+		{"partial segment", routes, "/docsearch", root},
+		{"nested prefix", routes, "/docs/api", api},
+		{"nested page", routes, "/docs/api/v1/users", api},
+		{"nested partial", routes, "/docs/apis", docs},
+		{"traversal out of prefix", routes, "/docs/../admin", root},
+		{"traversal out of nested", routes, "/docs/api/../../admin", root},
+		{"traversal into nested", routes, "/docs/intro/../api/v1", api},
+		{"traversal above root", routes, "/../../docs/intro", docs},
+		{"dot segment", routes, "/docs/./intro", docs},
+		{"repeated slashes", routes, "//docs//api", api},
+		{"empty path", routes, "", root},
+		{"no root route", routes[:2], "/about", nil},
+		{"no root route traversal", routes[:2], "/docs/../about", nil},
+		{"no routes", nil, "/", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := findAppRoute(test.routes, test.path)
+			switch {
+			case test.expect == nil && route != nil:
+				t.Errorf("findAppRoute(%q) = %v, expected nil", test.path, route)
+			case test.expect != nil && route == nil:
+				t.Errorf("findAppRoute(%q) = nil, expected %v", test.path, test.expect.Name)
+			case route != nil && route.App != test.expect:
+				t.Errorf("findAppRoute(%q) = %v (%v), expected %v", test.path, route, route.App.Name, test.expect.Name)
+			}
+		})
+	}
+}
+
+func TestAppRouteStripPrefix(t *testing.T) {
+	route, err := NewAppRoute("example.com/docs", &Application{Name: "docs"})
+	if err != nil {
+		t.Fatalf("NewAppRoute error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		path    string
+		rawPath string
+		escaped string
+		prefix  string
+	}{
+		{"prefix exact", "/docs", "/", "", "/", "/docs"},
+		{"prefix slash", "/docs/", "/", "", "/", "/docs"},
+		{"prefix page", "/docs/intro", "/intro", "", "/intro", "/docs"},
+		{"trailing slash", "/docs/intro/", "/intro/", "", "/intro/", "/docs"},
+		{"dot segment", "/docs/./intro", "/intro", "", "/intro", "/docs"},
+		{"repeated slashes", "/docs//intro", "/intro", "", "/intro", "/docs"},
+		{"traversal within", "/docs/a/../b", "/b", "", "/b", "/docs"},
+		{"traversal out", "/docs/../admin", "/admin", "", "/admin", ""},
+		{"encoded traversal out", "/docs/%2e%2e/admin", "/admin", "", "/admin", ""},
+		{"encoded slash", "/docs/a%2Fb", "/a/b", "/a%2Fb", "/a%2Fb", "/docs"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.target, nil)
+			route.StripPrefix(req)
+			if req.URL.Path != test.path || req.URL.RawPath != test.rawPath {
+				t.Errorf("StripPrefix(%q) path = %q (raw %q), expected %q (raw %q)", test.target, req.URL.Path, req.URL.RawPath, test.path, test.rawPath)
+			}
+			if escaped := req.URL.EscapedPath(); escaped != test.escaped {
+				t.Errorf("StripPrefix(%q) escaped path = %q, expected %q", test.target, escaped, test.escaped)
+			}
+			if prefix := req.Header.Get("X-Forwarded-Prefix"); prefix != test.prefix {
+				t.Errorf("StripPrefix(%q) X-Forwarded-Prefix = %q, expected %q", test.target, prefix, test.prefix)
+			}
+		})
+	}
+}
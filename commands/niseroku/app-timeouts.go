@@ -25,6 +25,7 @@ type AppTimeouts struct {
 	SlugStartup   *time.Duration `toml:"slug-startup,omitempty"`
 	ReadyInterval *time.Duration `toml:"ready-interval,omitempty"`
 	OriginRequest *time.Duration `toml:"origin-request,omitempty"`
+	UpgradeIdle   *time.Duration `toml:"upgrade-idle,omitempty"`
 }
 
 func (t AppTimeouts) String() (s string) {
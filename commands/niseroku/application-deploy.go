@@ -52,6 +52,12 @@ func (a *Application) IsDeploying() (locked bool) {
 }
 
 func (a *Application) Deploy() (err error) {
+	defer func() {
+		if err != nil {
+			a.Config.NotifyProxyMetric("deploy", a.Name, "failed")
+		}
+	}()
+
 	if err = a.lockDeploy(); err != nil {
 		return
 	}
@@ -90,19 +96,26 @@ func (a *Application) Deploy() (err error) {
 		}
 	}
 
-	if err = a.migrateAppSlug(targetSlug); err != nil {
+	canary := label == "next" && a.Canary.Enabled()
+	if err = a.migrateAppSlug(targetSlug, canary); err != nil {
 		a.LogErrorF("error migrating %v slug: %v\n", label, targetSlug.Name)
 		a.unlockDeploy()
 		return
 	}
 	targetSlug.RefreshWorkers()
 	a.LogInfoF("migrated to %v slug: %v\n", label, targetSlug)
+	a.Config.NotifyProxyMetric("deploy", a.Name, "succeeded")
+	if label == "same" || label == "this" {
+		// the workers of the live slug were started again, whether restarted
+		// on request or replacing stopped and crashed workers
+		a.Config.NotifyProxyMetric("restart", a.Name)
+	}
 	a.unlockDeploy()
 	<-a.awaitWorkersDone
 	return
 }
 
-func (a *Application) migrateAppSlug(slug *Slug) (err error) {
+func (a *Application) migrateAppSlug(slug *Slug, canary bool) (err error) {
 	a.LogInfoF("migrating to app slug: %v\n", slug.Name)
 	workersReady := make(chan bool)
 	go func() {
@@ -113,7 +126,11 @@ func (a *Application) migrateAppSlug(slug *Slug) (err error) {
 	}()
 	<-workersReady
 	if err == nil {
-		err = a.transitionAppToNextSlug(slug.App)
+		if canary {
+			err = a.deployCanary(slug)
+		} else {
+			err = a.transitionAppToNextSlug(slug.App)
+		}
 	}
 	return
 }
@@ -157,3 +174,95 @@ func (a *Application) transitionAppToNextSlug(app *Application) (err error) {
 	a.Config.SignalReloadReverseProxy()
 	return
 }
+
+// deployCanary registers the next slug as a canary with the reverse-proxy and
+// waits for the canary to be promoted or aborted, transitioning to the next
+// slug or rolling it back accordingly; the next slug is also rolled back if no
+// decision is made within the canary duration and a grace period, or if the
+// reverse-proxy cannot be reached for too many polls in a row
+func (a *Application) deployCanary(slug *Slug) (err error) {
+	args := ProxyControlCanaryArgs{App: a.Name, Action: CanaryActionStart, Slug: slug.Name}
+	var status ProxyControlCanary
+	if ee := a.Config.CallProxyControl("canary", args, &status); ee != nil {
+		a.LogErrorF("error starting canary, transitioning without one: %v\n", ee)
+		err = a.transitionAppToNextSlug(slug.App)
+		return
+	}
+	a.LogInfoF("canary started with %d%% of requests: %v\n", status.Percent, slug.Name)
+
+	deadline := time.Now().Add(a.Canary.GetDuration() + DefaultCanaryGracePeriod)
+	ticker := time.NewTicker(DefaultCanaryPollInterval)
+	defer ticker.Stop()
+	var controlErrors int
+	for status.Decision == "" {
+		<-ticker.C
+		if time.Now().After(deadline) {
+			status.Decision = CanaryDecisionAbort
+			status.Reason = "no canary decision before the deadline"
+			break
+		}
+		var latest ProxyControlCanary
+		args.Action = CanaryActionStatus
+		if ee := a.Config.CallProxyControl("canary", args, &latest); ee != nil {
+			// the reverse-proxy may be restarting
+			a.LogErrorF("error polling canary status: %v\n", ee)
+		} else if !latest.Active {
+			// the reverse-proxy restarted and lost track of the canary
+			args.Action = CanaryActionStart
+			if ee = a.Config.CallProxyControl("canary", args, &latest); ee != nil {
+				a.LogErrorF("error resuming canary: %v\n", ee)
+			} else {
+				status, controlErrors = latest, 0
+				continue
+			}
+		} else {
+			status, controlErrors = latest, 0
+			continue
+		}
+		if controlErrors += 1; controlErrors >= DefaultCanaryMaxControlErrors {
+			status.Decision = CanaryDecisionAbort
+			status.Reason = fmt.Sprintf("reverse-proxy unreachable for %d polls", controlErrors)
+		}
+	}
+	a.LogInfoF("canary decision: %v - %v (%d/%d canary errors, %d/%d baseline errors)\n", status.Decision, status.Reason, status.CanaryErrors, status.CanaryRequests, status.BaselineErrors, status.BaselineRequests)
+
+	if status.Decision == CanaryDecisionPromote {
+		err = a.transitionAppToNextSlug(slug.App)
+	} else if err = a.rollbackNextSlug(slug); err == nil {
+		err = fmt.Errorf("canary aborted: %v", status.Reason)
+	}
+
+	args.Action = CanaryActionEnd
+	if ee := a.Config.CallProxyControl("canary", args, nil); ee != nil {
+		a.LogErrorF("error ending canary: %v\n", ee)
+	}
+	return
+}
+
+// rollbackNextSlug discards the next slug, leaving this slug in place
+func (a *Application) rollbackNextSlug(slug *Slug) (err error) {
+	a.NextSlug = ""
+	if ee := a.Save(true); ee != nil {
+		err = fmt.Errorf("error saving: %v - %v\n", a.Name, ee)
+		return
+	}
+	if ee := a.Config.Reload(); ee != nil {
+		err = fmt.Errorf("error reloading: %v - %v", a.Name, ee)
+		return
+	}
+
+	a.LogInfoF("sending reverse-proxy reload signal\n")
+	a.Config.SignalReloadReverseProxy()
+
+	if !a.Config.KeepSlugs {
+		if ee := slug.Destroy(); ee != nil {
+			a.LogErrorF("error destroying slug: %v - %v", slug.Name, ee)
+		}
+	} else {
+		stopped := slug.StopAll()
+		a.LogInfoF("slug stopped %d instances: %v", stopped, slug.Name)
+	}
+
+	a.LogInfoF("app rolled back to slug: %v\n", a.ThisSlug)
+	return
+}
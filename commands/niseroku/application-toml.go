@@ -20,6 +20,10 @@ var applicationTomlComments = TomlComments{
 		Lines: []string{
 			": domains           (string...)",
 			":    * one or more domains routed to this app",
+			":    * domains may include a path prefix, ie: example.com/docs",
+			":    * the longest matching path prefix is routed to",
+			":    * a path prefix may be mounted within another app's root route,",
+			":      mounting within another app's path prefix is a conflict",
 		},
 	},
 	{
@@ -58,6 +62,16 @@ var applicationTomlComments = TomlComments{
 			":     * this setting is overwritten during deployments",
 		},
 	},
+	{
+		Statement: "socket",
+		Lines: []string{
+			": socket            (bool)",
+			":     * run slug workers on unix sockets instead of tcp ports",
+			":     * the socket path is given to workers in the SOCKET environment",
+			":       variable instead of the PORT, no ports are reserved for them",
+			":     * workers are ready once their socket appears",
+		},
+	},
 	{
 		Statement: "[timeouts]",
 		Lines: []string{
@@ -87,6 +101,246 @@ var applicationTomlComments = TomlComments{
 			":     * maximum time to allow slugs to perform a given request",
 		},
 	},
+	{
+		Statement: "upgrade-idle",
+		Lines: []string{
+			": upgrade-idle      (time.Duration)",
+			":     * maximum idle time for upgraded (websocket) connections",
+		},
+	},
+	{
+		Statement: "[proxy]",
+		Lines: []string{
+			": [proxy]           (section)",
+			":     * per-app reverse-proxy settings",
+		},
+	},
+	{
+		Statement: "balance",
+		Lines: []string{
+			": balance           (string)",
+			":     * how requests are distributed across live web workers",
+			":     * one of: round-robin (default), least-conn or random-two",
+		},
+	},
+	{
+		Statement: "strip-prefix",
+		Lines: []string{
+			": strip-prefix      (bool)",
+			":     * remove the domains path prefix before proxying requests",
+			":     * ie: example.com/docs/page is proxied as /page",
+		},
+	},
+	{
+		Statement: "max-body-size",
+		Lines: []string{
+			": max-body-size     (int)",
+			":     * maximum request body size in bytes, larger requests get a 413",
+			":     * zero inherits niseroku.toml [server] max-body-size, -1 for no limit",
+		},
+	},
+	{
+		Statement: "force-https",
+		Lines: []string{
+			": force-https       (bool)",
+			":     * redirect plain http requests to https when enable-ssl is true",
+			":     * defaults to true, set false to serve this app over plain http",
+		},
+	},
+	{
+		Statement: "hsts-max-age",
+		Lines: []string{
+			": hsts-max-age      (time.Duration)",
+			":     * add a Strict-Transport-Security header to https responses",
+			":     * requires force-https, omit to not send the header",
+		},
+	},
+	{
+		Statement: "hsts-include-subdomains",
+		Inline:    ": (bool) add includeSubDomains to the hsts header",
+	},
+	{
+		Statement: "hsts-preload",
+		Inline:    ": (bool) add preload to the hsts header",
+	},
+	{
+		Statement: "[proxy.retry-budget]",
+		Lines: []string{
+			": [proxy.retry-budget] (section)",
+			":     * limits [retry] failover so that a failing app is not flooded",
+			":     * ratio (float) - retries allowed per request (default 0.2)",
+			":     * minimum (float) - retries allowed regardless of the ratio, also",
+			":       the most retries saved up by the ratio (default 10)",
+		},
+	},
+	{
+		Statement: "[health-check]",
+		Lines: []string{
+			": [health-check]    (section)",
+			":     * active http health checks performed by the reverse-proxy",
+			":     * unhealthy workers are not given requests until they recover",
+			":     * path (string) - request path to check, enables health checks",
+			":     * interval, timeout (time.Duration) - check frequency and limit",
+			":     * healthy-threshold (int) - consecutive passes to become healthy",
+			":     * unhealthy-threshold (int) - consecutive failures to become unhealthy",
+		},
+	},
+	{
+		Statement: "[retry]",
+		Lines: []string{
+			": [retry]           (section)",
+			":     * failover of origin requests to other live workers",
+			":     * connection errors are retried for requests without a body and",
+			":       other errors only for idempotent requests without a body",
+			":     * attempts (int) - origin requests per client request (default 3)",
+			":     * the number of retries is limited by [proxy.retry-budget]",
+			":     * breaker-failures (int) - consecutive failures, including refused",
+			":       connections, which stop requests to a worker (default 5)",
+			":     * breaker-cooldown (time.Duration) - time before a stopped worker is",
+			":       tried again (default 10s)",
+		},
+	},
+	{
+		Statement: "[canary]",
+		Lines: []string{
+			": [canary]          (section)",
+			":     * deployments of a next slug start with a canary phase, the",
+			":       reverse-proxy sends some requests to the next slug and",
+			":       compares 5xx rates before promoting or rolling back",
+			":     * percent (int) - percentage of requests sent to the next slug",
+			":     * cookie, header (string) - name or name=value of a request",
+			":       cookie or header which always selects the next slug",
+			":     * duration (time.Duration) - time before promoting (default 10m)",
+			":     * min-requests (int) - canary requests before comparing (default 100)",
+			":     * max-error-rate (float) - how much higher the canary 5xx rate may",
+			":       be than the this-slug 5xx rate (default 0.05)",
+			":     * see: niseroku app canary --help",
+		},
+	},
+	{
+		Statement: "[mirror]",
+		Lines: []string{
+			": [mirror]          (section)",
+			":     * copies a sample of requests to a shadow slug, the shadow",
+			":       responses are discarded and compared with the responses",
+			":       clients received, see: niseroku status",
+			":     * slug (string) - \"next\" (default) or the name or commit of",
+			":       another slug of this app, its workers must already be running",
+			":     * rate (float) - ratio of requests to copy, ie: 0.1 for 10%",
+			":     * methods (string...) - only copy requests with these methods",
+			":       (default GET, HEAD and OPTIONS), list other methods only if",
+			":       the shadow slug has no side effects shared with the live slug",
+			":     * max-concurrent (int) - mirrored requests in flight, any more",
+			":       are not copied (default 10)",
+			":     * max-body-size (int) - requests with larger bodies are not",
+			":       copied (default 1 MiB)",
+		},
+	},
+	{
+		Statement: "[proxy-limit]",
+		Lines: []string{
+			": [proxy-limit]     (section)",
+			":     * per-app reverse-proxy request rate-limiting settings",
+			":     * same settings as the niseroku.toml [proxy-limit] section",
+			":     * unset numeric settings are inherited from niseroku.toml",
+			":     * log-allowed, log-delayed and log-limited are not inherited",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[access]",
+		Lines: []string{
+			": [access]          (section)",
+			":     * per-app client network access lists, checked after niseroku.toml [access]",
+			":     * allow (string...) - only allow clients within these CIDR networks",
+			":     * deny (string...) - deny clients within these CIDR networks",
+			":     * denied-status (int) - response status code for denied requests",
+			":     * denied-message (string) - response body for denied requests",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[auth]",
+		Lines: []string{
+			": [auth]            (section)",
+			":     * http basic-auth required by the reverse-proxy, checked after [access]",
+			":     * file (path) - htpasswd file of user:bcrypt-hash lines, relative",
+			":       paths are within auth.d, use be-bcrypt to generate hashes",
+			":     * realm (string) - basic-auth realm (default \"Restricted\")",
+			":     * exempt (string...) - request paths which do not require auth",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[[certificates]]",
+		Lines: []string{
+			": [[certificates]]  (section list)",
+			":     * static ssl certificates, preferred over let's encrypt",
+			":     * domain (string) - domain name or wildcard (*.example.com)",
+			":     * cert-file, key-file (path) - PEM files within the proxy secrets path",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[[redirects]]",
+		Lines: []string{
+			": [[redirects]]     (section list)",
+			":     * redirect requests for other domains, ie: www to apex",
+			":     * from-host (string) - domain to redirect, not listed in domains",
+			":     * to-host (string) - domain to redirect to",
+			":     * status (int) - 301 (default), 302, 303, 307 or 308",
+			":     * keep-path (bool) - keep the request path and query (default true)",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[error-pages]",
+		Lines: []string{
+			": [error-pages]     (section)",
+			":     * custom html error pages, ie: 502.html, 503.html",
+			":     * pages are html templates with .Status, .StatusText, .App, .Host,",
+			":       .Path, .RequestId and .RetryAfter values",
+			":     * path (string) - directory within error-pages.d, defaults to the app name",
+			":     * files (section) - status code keys with file name values",
+			":     * retry-after (time.Duration) - Retry-After for maintenance and 503 pages",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[compression]",
+		Lines: []string{
+			": [compression]     (section)",
+			":     * per-app response compression, overrides niseroku.toml [compression]",
+			":     * enable (bool) - compress responses not already encoded by the origin",
+			":     * encodings (string...) - zstd and/or gzip, in order of preference",
+			":     * content-types (string...) - media types to compress, ie: text/*",
+			":     * min-size (int) - minimum Content-Length to compress",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[cache]",
+		Lines: []string{
+			": [cache]           (section)",
+			":     * reverse-proxy cache of responses the origin marks as cacheable",
+			":       with Cache-Control max-age, s-maxage or Expires headers",
+			":     * enable (bool) - cache responses for this app",
+			":     * max-size (int) - total bytes cached, in memory and on disk",
+			":     * memory-size (int) - bytes held in memory before overflowing to disk",
+			":     * max-entry-size (int) - largest response body to cache",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
+	{
+		Statement: "[access-log]",
+		Lines: []string{
+			": [access-log]      (section)",
+			":     * per-app access log format, overrides niseroku.toml [access-log]",
+			":     * format (string) - niseroku, combined, json or template",
+			":     * template (string) - text/template using the access log entry fields",
+			":     * requires niseroku-proxy reload if changed",
+		},
+	},
 	{
 		Statement: "[settings]",
 		Lines: []string{
@@ -26,6 +26,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/go-git/go-git/v5"
+	"github.com/kataras/requestid"
 
 	clpath "github.com/go-corelibs/path"
 
@@ -43,6 +44,34 @@ type Application struct {
 
 	Timeouts AppTimeouts `toml:"timeouts,omitempty"`
 
+	Proxy AppProxy `toml:"proxy,omitempty"`
+
+	HealthCheck *AppHealthCheck `toml:"health-check,omitempty"`
+
+	Retry *AppRetry `toml:"retry,omitempty"`
+
+	Canary *AppCanary `toml:"canary,omitempty"`
+
+	Mirror *AppMirror `toml:"mirror,omitempty"`
+
+	ProxyLimit *RateLimit `toml:"proxy-limit,omitempty"`
+
+	Access *AccessConfig `toml:"access,omitempty"`
+
+	Auth *AppAuth `toml:"auth,omitempty"`
+
+	Certificates []*AppCertificate `toml:"certificates,omitempty"`
+
+	Redirects []*AppRedirect `toml:"redirects,omitempty"`
+
+	ErrorPages *AppErrorPages `toml:"error-pages,omitempty"`
+
+	Compression *CompressionConfig `toml:"compression,omitempty"`
+
+	Cache *AppCache `toml:"cache,omitempty"`
+
+	AccessLogFormat *AccessLogConfig `toml:"access-log,omitempty"`
+
 	Settings map[string]interface{} `toml:"settings,omitempty"`
 
 	Origin AppOrigin `toml:"origin"`
@@ -172,6 +201,45 @@ func (a *Application) Load() (err error) {
 			err = fmt.Errorf("domains setting not found")
 		}
 	}
+	if err != nil {
+		return
+	}
+
+	if err = a.Proxy.Validate(); err != nil {
+		return
+	} else if err = a.HealthCheck.Validate(); err != nil {
+		return
+	} else if err = a.Retry.Validate(); err != nil {
+		return
+	} else if err = a.Canary.Validate(); err != nil {
+		return
+	} else if err = a.Mirror.Validate(); err != nil {
+		return
+	} else if err = a.validateProxyLimit(); err != nil {
+		return
+	} else if err = a.Access.Parse(); err != nil {
+		return
+	} else if err = a.Auth.Validate(); err != nil {
+		return
+	} else if err = a.ErrorPages.Validate(); err != nil {
+		return
+	} else if err = a.Compression.Validate(); err != nil {
+		return
+	} else if err = a.Cache.Validate(); err != nil {
+		return
+	} else if err = a.AccessLogFormat.Validate(); err != nil {
+		return
+	}
+	for _, cert := range a.Certificates {
+		if err = cert.Validate(); err != nil {
+			return
+		}
+	}
+	for _, redirect := range a.Redirects {
+		if err = redirect.Validate(); err != nil {
+			return
+		}
+	}
 
 	if a.AptEnjin != nil {
 		a.AptBasePath = fmt.Sprintf("%v/%v", a.Config.Paths.VarAptRoot, a.Name)
@@ -241,17 +309,35 @@ func (a *Application) LogInfoF(format string, argv ...interface{}) {
 }
 
 func (a *Application) LogAccessF(status int, remoteAddr string, r *http.Request, start time.Time) {
-	beIo.AppendF(a.AccessLog,
-		"[%v] [%v] %v - %v - (%d) - %v %v (%v)\n",
-		a.Name,
-		time.Now().Format("20060102-150405"),
-		remoteAddr,
-		r.Host,
-		status,
-		r.Method,
-		r.URL.Path,
-		time.Now().Sub(start).String(),
-	)
+	now := time.Now()
+	entry := &AccessLogEntry{
+		Time:       now,
+		App:        a.Name,
+		Host:       r.Host,
+		RemoteAddr: remoteAddr,
+		Method:     r.Method,
+		Uri:        r.URL.RequestURI(),
+		Path:       r.URL.Path,
+		Proto:      r.Proto,
+		Status:     status,
+		Latency:    now.Sub(start),
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+		RequestId:  requestid.Get(r),
+	}
+	getAccessRecord(r).Apply(entry)
+	beIo.AppendF(a.AccessLog, "%s", a.GetAccessLogFormat().Render(entry))
+}
+
+// GetAccessLogFormat returns the app's [access-log] settings if present,
+// otherwise the niseroku.toml [access-log] settings
+func (a *Application) GetAccessLogFormat() (format *AccessLogConfig) {
+	if a.AccessLogFormat.IsSet() {
+		format = a.AccessLogFormat
+	} else if a.Config != nil {
+		format = &a.Config.AccessLogFormat
+	}
+	return
 }
 
 func (a *Application) LogError(err error) {
@@ -0,0 +1,70 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-enjin/enjenv/pkg/io"
+	"github.com/go-enjin/enjenv/pkg/service/common"
+)
+
+func makeCommandAppCachePurge(c *Command, app *cli.App) (cmd *cli.Command) {
+	cmd = &cli.Command{
+		Name:      "cache-purge",
+		Usage:     "purge the reverse-proxy response cache of an application",
+		UsageText: app.Name + " niseroku app cache-purge <name> [path-prefix]",
+		Action:    c.actionAppCachePurge,
+	}
+	return
+}
+
+func (c *Command) actionAppCachePurge(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	io.LogFile = ""
+
+	argc := ctx.NArg()
+	if argc < 1 || argc > 2 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+
+	name := ctx.Args().Get(0)
+	if _, ok := c.config.Applications[name]; !ok {
+		err = fmt.Errorf("application not found: %v", name)
+		return
+	}
+
+	if err = common.DropPrivilegesTo(c.config.RunAs.User, c.config.RunAs.Group); err != nil {
+		err = fmt.Errorf("error dropping root privileges: %v", err)
+		return
+	}
+
+	argv := ctx.Args().Slice()
+	var response string
+	if response, err = c.config.CallProxyControlCommand("cache-purge", argv...); err != nil {
+		err = fmt.Errorf("error calling reverse-proxy: %v", err)
+		return
+	} else if response = strings.TrimSpace(response); strings.HasPrefix(response, "ERR: ") {
+		err = fmt.Errorf("%v", strings.TrimPrefix(response, "ERR: "))
+		return
+	}
+	io.STDOUT("%v\n", response)
+	return
+}
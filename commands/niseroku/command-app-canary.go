@@ -0,0 +1,120 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+
+	beIo "github.com/go-enjin/enjenv/pkg/io"
+	"github.com/go-enjin/enjenv/pkg/service/common"
+)
+
+func makeCommandAppCanary(c *Command, app *cli.App) (cmd *cli.Command) {
+	cmd = &cli.Command{
+		Name:      "canary",
+		Usage:     "inspect or steer the canary deployment of an application",
+		UsageText: app.Name + " niseroku app canary <name> [--percent N] [--promote] [--abort]",
+		Description: `Without options, shows the current canary status. The deploy process waits
+for the canary to be promoted or aborted, automatically or with this command.
+
+A canary is only started by deploying a new slug of an application with a
+[canary] section in its app.toml which sets the percent, cookie or header;
+--percent changes the percentage of requests of a canary already in progress.`,
+		Action: c.actionAppCanary,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "percent",
+				Usage: "change the percentage of requests routed to the canary in progress",
+				Value: -1,
+			},
+			&cli.BoolFlag{
+				Name:  "promote",
+				Usage: "promote the canary to the live slug now",
+			},
+			&cli.BoolFlag{
+				Name:  "abort",
+				Usage: "abort the canary and roll back to the live slug",
+			},
+			cmdJsonFlag,
+		},
+	}
+	return
+}
+
+func (c *Command) actionAppCanary(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	beIo.LogFile = ""
+
+	if ctx.NArg() != 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+
+	name := ctx.Args().Get(0)
+	if _, ok := c.config.Applications[name]; !ok {
+		err = fmt.Errorf("application not found: %v", name)
+		return
+	}
+
+	args := ProxyControlCanaryArgs{App: name, Action: CanaryActionStatus}
+	switch {
+	case ctx.Bool("promote") && ctx.Bool("abort"):
+		err = fmt.Errorf("--promote and --abort are mutually exclusive")
+		return
+	case ctx.Bool("promote"):
+		args.Action = CanaryActionPromote
+	case ctx.Bool("abort"):
+		args.Action = CanaryActionAbort
+	case ctx.Int("percent") >= 0:
+		args.Action = CanaryActionPercent
+		args.Percent = ctx.Int("percent")
+	}
+
+	if err = common.DropPrivilegesTo(c.config.RunAs.User, c.config.RunAs.Group); err != nil {
+		err = fmt.Errorf("error dropping root privileges: %v", err)
+		return
+	}
+
+	var status ProxyControlCanary
+	if err = c.config.CallProxyControl("canary", args, &status); err != nil {
+		err = fmt.Errorf("error calling reverse-proxy: %v", err)
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(status)
+		return
+	} else if !status.Active {
+		beIo.STDOUT("%v canary not in progress\n", name)
+		return
+	}
+
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "APP\t%v\n", status.App)
+		_, _ = fmt.Fprintf(tw, "SLUG\t%v\n", status.Slug)
+		_, _ = fmt.Fprintf(tw, "PERCENT\t%d%%\n", status.Percent)
+		_, _ = fmt.Fprintf(tw, "STARTED\t%v\n", status.Started.Format("2006-01-02 15:04:05"))
+		_, _ = fmt.Fprintf(tw, "DURATION\t%v\n", status.Duration)
+		_, _ = fmt.Fprintf(tw, "BASELINE\t%d errors / %d requests\n", status.BaselineErrors, status.BaselineRequests)
+		_, _ = fmt.Fprintf(tw, "CANARY\t%d errors / %d requests\n", status.CanaryErrors, status.CanaryRequests)
+		_, _ = fmt.Fprintf(tw, "DECISION\t%v\n", CheckAB(status.Decision, "-", status.Decision != ""))
+		if status.Reason != "" {
+			_, _ = fmt.Fprintf(tw, "REASON\t%v\n", status.Reason)
+		}
+	})
+	return
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/go-enjin/enjenv/pkg/service/common"
+
+	beIo "github.com/go-enjin/enjenv/pkg/io"
+)
+
+func makeCommandCerts(c *Command, app *cli.App) (cmd *cli.Command) {
+	cmd = &cli.Command{
+		Name:      "certs",
+		Usage:     "list the ssl certificates cached by the reverse-proxy",
+		UsageText: app.Name + " niseroku certs [--renew <domain>]",
+		Action:    c.actionCerts,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "renew",
+				Usage: "force the reverse-proxy to renew the certificate for the given domain",
+			},
+		},
+	}
+	return
+}
+
+type CachedCert struct {
+	File    string
+	Domains []string
+	Issuer  string
+	Expires time.Time
+}
+
+func (c *Command) actionCerts(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	if err = common.DropPrivilegesTo(c.config.RunAs.User, c.config.RunAs.Group); err != nil {
+		return
+	}
+
+	if domain := ctx.String("renew"); domain != "" {
+		var response string
+		if response, err = c.config.CallProxyControlCommand("renew-cert", domain); err != nil {
+			err = fmt.Errorf("error calling reverse-proxy: %v", err)
+			return
+		} else if response = strings.TrimSpace(response); strings.HasPrefix(response, "ERR: ") {
+			err = fmt.Errorf("%v", strings.TrimPrefix(response, "ERR: "))
+			return
+		}
+		beIo.STDOUT("%v\n", response)
+		return
+	}
+
+	var certs []*CachedCert
+	if certs, err = findCachedCerts(c.config.Paths.ProxySecrets); err != nil {
+		return
+	}
+
+	buf := bytes.NewBuffer([]byte(""))
+	tw := tabwriter.NewWriter(io.Writer(buf), 8, 2, 2, ' ', tabwriter.FilterHTML)
+	_, _ = tw.Write([]byte("[ CERTIFICATE ]\t[ DOMAINS ]\t[ ISSUER ]\t[ EXPIRES ]\n"))
+	now := time.Now()
+	for _, cert := range certs {
+		expires := cert.Expires.Format(time.RFC3339)
+		if cert.Expires.Before(now) {
+			expires += " (expired)"
+		}
+		_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%s\t%s\t%s\n", cert.File, strings.Join(cert.Domains, ","), cert.Issuer, expires)))
+	}
+	_ = tw.Flush()
+	beIo.STDOUT(buf.String())
+	return
+}
+
+// findCachedCerts returns the leaf certificate details of all PEM files
+// within the secrets path, sorted by file name
+func findCachedCerts(secrets string) (certs []*CachedCert, err error) {
+	err = filepath.WalkDir(secrets, func(path string, d fs.DirEntry, ee error) error {
+		if ee != nil {
+			return ee
+		} else if d.IsDir() || strings.HasSuffix(path, "+token") {
+			return nil
+		}
+		data, ee := os.ReadFile(path)
+		if ee != nil {
+			return ee
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			// the first certificate is the leaf
+			if leaf, eee := x509.ParseCertificate(block.Bytes); eee == nil {
+				file, _ := filepath.Rel(secrets, path)
+				certs = append(certs, &CachedCert{
+					File:    file,
+					Domains: leaf.DNSNames,
+					Issuer:  leaf.Issuer.CommonName,
+					Expires: leaf.NotAfter,
+				})
+			}
+			break
+		}
+		return nil
+	})
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].File < certs[j].File
+	})
+	return
+}
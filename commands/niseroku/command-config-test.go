@@ -15,6 +15,8 @@
 package niseroku
 
 import (
+	"fmt"
+
 	"github.com/urfave/cli/v2"
 
 	beIo "github.com/go-enjin/enjenv/pkg/io"
@@ -24,6 +26,17 @@ func (c *Command) actionConfigTest(ctx *cli.Context) (err error) {
 	if err = c.Prepare(ctx); err != nil {
 		return
 	}
+	nested, conflicts := c.config.FindNestedRoutes()
+	for _, notice := range nested {
+		beIo.STDOUT("NOTICE: %v\n", notice)
+	}
+	for _, conflict := range conflicts {
+		beIo.STDERR("CONFLICT: %v\n", conflict)
+	}
+	if len(conflicts) > 0 {
+		err = fmt.Errorf("%d conflicting routes found", len(conflicts))
+		return
+	}
 	beIo.STDOUT("OK\n")
 	return
 }
@@ -102,6 +102,8 @@ func (c *Command) actionFixFs(ctx *cli.Context) (err error) {
 		c.config.Paths.Etc,
 		c.config.Paths.EtcApps,
 		c.config.Paths.EtcUsers,
+		c.config.Paths.EtcErrorPages,
+		c.config.Paths.EtcAuth,
 		c.config.Paths.Tmp,
 		c.config.Paths.TmpRun,
 		c.config.Paths.TmpClone,
@@ -110,6 +112,7 @@ func (c *Command) actionFixFs(ctx *cli.Context) (err error) {
 		c.config.Paths.VarLogs,
 		c.config.Paths.VarSlugs,
 		c.config.Paths.VarCache,
+		c.config.Paths.VarProxyCache,
 		c.config.Paths.VarRepos,
 		c.config.Paths.VarAptRoot,
 	}
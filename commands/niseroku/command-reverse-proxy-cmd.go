@@ -0,0 +1,375 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+
+	beIo "github.com/go-enjin/enjenv/pkg/io"
+)
+
+var cmdJsonFlag = &cli.BoolFlag{
+	Name:  "json",
+	Usage: "output the response data as JSON",
+}
+
+func makeCommandReverseProxyCmd(c *Command, app *cli.App) (cmd *cli.Command) {
+	cmd = &cli.Command{
+		Name:      "cmd",
+		Usage:     "run proxy-control commands",
+		UsageText: app.Name + " niseroku reverse-proxy cmd <name> [argv...]",
+		Description: `Typed subcommands use the JSON control protocol, any other name is sent
+to the reverse-proxy as a plain text command with the remaining argv.`,
+		Action: c.actionProxyControlCommand,
+		Subcommands: []*cli.Command{
+			{
+				Name:      "info",
+				Usage:     "show the control protocol version and commands",
+				UsageText: app.Name + " niseroku reverse-proxy cmd info",
+				Action:    c.actionProxyControlInfo,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "apps",
+				Usage:     "list applications known to the reverse-proxy",
+				UsageText: app.Name + " niseroku reverse-proxy cmd apps",
+				Action:    c.actionProxyControlApps,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "routes",
+				Usage:     "list the reverse-proxy routes and redirects",
+				UsageText: app.Name + " niseroku reverse-proxy cmd routes",
+				Action:    c.actionProxyControlRoutes,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "workers",
+				Usage:     "list live workers and their state",
+				UsageText: app.Name + " niseroku reverse-proxy cmd workers [app]",
+				Action:    c.actionProxyControlWorkers,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "mirrors",
+				Usage:     "compare mirrored requests with their shadow slug responses",
+				UsageText: app.Name + " niseroku reverse-proxy cmd mirrors [app]",
+				Action:    c.actionProxyControlMirrors,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "maintenance",
+				Usage:     "toggle maintenance mode of an application",
+				UsageText: app.Name + " niseroku reverse-proxy cmd maintenance <app> <on|off>",
+				Action:    c.actionProxyControlMaintenance,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+			{
+				Name:      "drain",
+				Usage:     "stop sending new requests to a worker",
+				UsageText: app.Name + " niseroku reverse-proxy cmd drain [--undo] <app> <port>",
+				Action:    c.actionProxyControlDrain,
+				Flags: []cli.Flag{
+					cmdJsonFlag,
+					&cli.BoolFlag{
+						Name:  "undo",
+						Usage: "resume sending requests to the worker",
+					},
+				},
+			},
+			{
+				Name:      "reload-app",
+				Usage:     "reload the configuration and workers of one application",
+				UsageText: app.Name + " niseroku reverse-proxy cmd reload-app <app>",
+				Action:    c.actionProxyControlReloadApp,
+				Flags:     []cli.Flag{cmdJsonFlag},
+			},
+		},
+	}
+	return
+}
+
+func (c *Command) actionProxyControlCommand(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	argv := ctx.Args().Slice()
+	if len(argv) < 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+	name := argv[0]
+	argv = argv[1:]
+	var response string
+	if response, err = c.config.CallProxyControlCommand(name, argv...); err != nil {
+		return
+	}
+	beIo.STDOUT("%v", response)
+	return
+}
+
+func (c *Command) actionProxyControlInfo(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	var info ProxyControlInfo
+	if err = c.config.CallProxyControl("info", nil, &info); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(info)
+		return
+	}
+	beIo.STDOUT("protocol version: %d\n", info.Version)
+	beIo.STDOUT("commands: %v\n", strings.Join(info.Commands, ", "))
+	return
+}
+
+func (c *Command) actionProxyControlApps(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	var apps []ProxyControlApp
+	if err = c.config.CallProxyControl("apps", nil, &apps); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(apps)
+		return
+	}
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "APP\tMAINTENANCE\tTHIS SLUG\tNEXT SLUG\tLIVE PORTS\tDOMAINS\n")
+		for _, app := range apps {
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				app.Name,
+				app.Maintenance,
+				CheckAB(app.ThisSlug, "-", app.ThisSlug != ""),
+				CheckAB(app.NextSlug, "-", app.NextSlug != ""),
+				CheckAB(formatPortList(app.LivePorts), "-", len(app.LivePorts) > 0),
+				strings.Join(app.Domains, ","),
+			)
+		}
+	})
+	return
+}
+
+func (c *Command) actionProxyControlRoutes(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	var routes ProxyControlRoutes
+	if err = c.config.CallProxyControl("routes", nil, &routes); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(routes)
+		return
+	}
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "HOST\tPREFIX\tAPP\n")
+		for _, route := range routes.Routes {
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\n", route.Host, route.Prefix, route.App)
+		}
+	})
+	if len(routes.Redirects) > 0 {
+		beIo.STDOUT("\n")
+		c.outputProxyControlTable(func(tw io.Writer) {
+			_, _ = fmt.Fprintf(tw, "FROM\tTO\tSTATUS\tAPP\n")
+			for _, redirect := range routes.Redirects {
+				_, _ = fmt.Fprintf(tw, "%v\t%v\t%d\t%v\n", redirect.FromHost, redirect.ToHost, redirect.Status, redirect.App)
+			}
+		})
+	}
+	return
+}
+
+func (c *Command) actionProxyControlWorkers(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	var args interface{}
+	if ctx.NArg() > 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	} else if ctx.NArg() == 1 {
+		args = ProxyControlAppArgs{App: ctx.Args().First()}
+	}
+	var workers []ProxyControlWorker
+	if err = c.config.CallProxyControl("workers", args, &workers); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(workers)
+		return
+	}
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "APP\tSLUG\tHASH\tPORT\tRUNNING\tREADY\tHEALTHY\tDRAINING\tCIRCUIT\tIN-FLIGHT\n")
+		for _, worker := range workers {
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%d\t%v\t%v\t%v\t%v\t%v\t%d\n",
+				worker.App, worker.Slug, worker.Hash, worker.Port,
+				worker.Running, worker.Ready, worker.Healthy, worker.Draining,
+				CheckAB("open", "closed", worker.CircuitOpen), worker.InFlight,
+			)
+		}
+	})
+	return
+}
+
+func (c *Command) actionProxyControlMirrors(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	var args interface{}
+	if ctx.NArg() > 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	} else if ctx.NArg() == 1 {
+		args = ProxyControlAppArgs{App: ctx.Args().First()}
+	}
+	var mirrors []ProxyControlMirror
+	if err = c.config.CallProxyControl("mirrors", args, &mirrors); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(mirrors)
+		return
+	}
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "APP\tSLUG\tRATE\tMIRRORED\tCOMPARED\tMISMATCHED\tPRIMARY 5XX\tSHADOW 5XX\tPRIMARY LATENCY\tSHADOW LATENCY\tDROPPED\tSKIPPED\tERRORS\n")
+		for _, mirror := range mirrors {
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%d\t%d\t%d\t%d\t%d\t%v\t%v\t%d\t%d\t%d\n",
+				mirror.App, mirror.Slug, mirror.Rate, mirror.Mirrored, mirror.Compared,
+				mirror.StatusMismatches, mirror.PrimaryErrors, mirror.ShadowErrors,
+				CheckAB(mirror.PrimaryLatency, "-", mirror.PrimaryLatency != ""),
+				CheckAB(mirror.ShadowLatency, "-", mirror.ShadowLatency != ""),
+				mirror.Dropped, mirror.Skipped, mirror.Errors,
+			)
+		}
+	})
+	var mismatches []string
+	for _, mirror := range mirrors {
+		for _, mismatch := range mirror.Mismatches {
+			mismatches = append(mismatches, fmt.Sprintf("%v\t%v\t%v\t%v\t%d\t%d\n",
+				mismatch.Time.Format("2006-01-02 15:04:05"), mirror.App, mismatch.Method, mismatch.Path,
+				mismatch.Primary, mismatch.Shadow,
+			))
+		}
+	}
+	if len(mismatches) > 0 {
+		beIo.STDOUT("\n")
+		c.outputProxyControlTable(func(tw io.Writer) {
+			_, _ = fmt.Fprintf(tw, "MISMATCHED\tAPP\tMETHOD\tPATH\tPRIMARY\tSHADOW\n")
+			for _, line := range mismatches {
+				_, _ = fmt.Fprint(tw, line)
+			}
+		})
+	}
+	return
+}
+
+func (c *Command) actionProxyControlMaintenance(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	if ctx.NArg() != 2 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+	argv := ctx.Args().Slice()
+	var enable bool
+	switch strings.ToLower(argv[1]) {
+	case "on":
+		enable = true
+	case "off":
+		enable = false
+	default:
+		if enable, err = strconv.ParseBool(argv[1]); err != nil {
+			err = fmt.Errorf("invalid maintenance setting: %v", argv[1])
+			return
+		}
+	}
+	err = c.callProxyControlAction(ctx, "maintenance", ProxyControlMaintenanceArgs{App: argv[0], Enable: enable},
+		"%v maintenance mode %v", argv[0], CheckAB("enabled", "disabled", enable))
+	return
+}
+
+func (c *Command) actionProxyControlDrain(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	if ctx.NArg() != 2 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+	argv := ctx.Args().Slice()
+	var port int
+	if port, err = strconv.Atoi(argv[1]); err != nil {
+		err = fmt.Errorf("invalid port: %v", argv[1])
+		return
+	}
+	drain := !ctx.Bool("undo")
+	err = c.callProxyControlAction(ctx, "drain", ProxyControlDrainArgs{App: argv[0], Port: port, Drain: drain},
+		"%v worker on port %d %v", argv[0], port, CheckAB("draining", "undrained", drain))
+	return
+}
+
+func (c *Command) actionProxyControlReloadApp(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	if ctx.NArg() != 1 {
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	}
+	name := ctx.Args().First()
+	err = c.callProxyControlAction(ctx, "reload-app", ProxyControlAppArgs{App: name}, "%v reloaded", name)
+	return
+}
+
+// callProxyControlAction sends a command which returns no data, printing the
+// given message on success
+func (c *Command) callProxyControlAction(ctx *cli.Context, command string, args interface{}, format string, argv ...interface{}) (err error) {
+	if err = c.config.CallProxyControl(command, args, nil); err != nil {
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(map[string]bool{"ok": true})
+		return
+	}
+	beIo.STDOUT(format+"\n", argv...)
+	return
+}
+
+func (c *Command) outputProxyControlJson(data interface{}) (err error) {
+	var output []byte
+	if output, err = json.MarshalIndent(data, "", "  "); err != nil {
+		return
+	}
+	beIo.STDOUT("%v\n", string(output))
+	return
+}
+
+func (c *Command) outputProxyControlTable(fn func(tw io.Writer)) {
+	buf := bytes.NewBuffer([]byte(""))
+	tw := tabwriter.NewWriter(io.Writer(buf), 8, 2, 2, ' ', tabwriter.FilterHTML)
+	fn(tw)
+	_ = tw.Flush()
+	beIo.STDOUT(buf.String())
+}
+
+func formatPortList(ports []int) (list string) {
+	var parts []string
+	for _, port := range ports {
+		parts = append(parts, strconv.Itoa(port))
+	}
+	list = strings.Join(parts, ",")
+	return
+}
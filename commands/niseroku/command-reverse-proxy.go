@@ -19,7 +19,6 @@ import (
 
 	"github.com/urfave/cli/v2"
 
-	"github.com/go-enjin/enjenv/pkg/io"
 	"github.com/go-enjin/enjenv/pkg/profiling"
 )
 
@@ -42,13 +41,7 @@ func makeCommandReverseProxy(c *Command, app *cli.App) (cmd *cli.Command) {
 				UsageText: app.Name + " niseroku reverse-proxy stop",
 				Action:    c.actionReverseProxyStop,
 			},
-			{
-				Name:      "cmd",
-				Usage:     "run proxy-control commands",
-				UsageText: app.Name + " niseroku reverse-proxy cmd <name> [argv...]",
-				Action:    c.actionProxyControlCommand,
-				Hidden:    true,
-			},
+			makeCommandReverseProxyCmd(c, app),
 		},
 	}
 	return
@@ -68,21 +61,3 @@ func (c *Command) actionReverseProxy(ctx *cli.Context) (err error) {
 	err = rp.Start()
 	return
 }
-
-func (c *Command) actionProxyControlCommand(ctx *cli.Context) (err error) {
-	if err = c.Prepare(ctx); err != nil {
-		return
-	}
-	argv := ctx.Args().Slice()
-	if len(argv) < 1 {
-		cli.ShowSubcommandHelpAndExit(ctx, 1)
-	}
-	name := argv[0]
-	argv = argv[1:]
-	var response string
-	if response, err = c.config.CallProxyControlCommand(name, argv...); err != nil {
-		return
-	}
-	io.STDOUT("%v", response)
-	return
-}
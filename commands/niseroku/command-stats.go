@@ -0,0 +1,126 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	beIo "github.com/go-enjin/enjenv/pkg/io"
+	"github.com/go-enjin/enjenv/pkg/service/common"
+)
+
+func makeCommandStats(c *Command, app *cli.App) (cmd *cli.Command) {
+	cmd = &cli.Command{
+		Name:      "stats",
+		Usage:     "display the reverse-proxy request history",
+		UsageText: app.Name + " niseroku stats [options] [app]",
+		Description: `Without an app, the history of all requests is displayed. Per-second
+history covers the last ten minutes and per-minute history the last day, unless
+changed with the [stats] niseroku.toml settings. Remote address history is
+only kept per-second.`,
+		Action: c.actionStats,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "how far back to display, in the Go time.Duration format",
+				Value: DefaultStatsSince.String(),
+			},
+			&cli.StringFlag{
+				Name:  "resolution",
+				Usage: "second or minute, defaults to the finest covering all of --since",
+			},
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "display the history of a host instead of an app",
+			},
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "display the history of a remote address instead of an app",
+			},
+			cmdJsonFlag,
+		},
+	}
+	return
+}
+
+func (c *Command) actionStats(ctx *cli.Context) (err error) {
+	if err = c.Prepare(ctx); err != nil {
+		return
+	}
+	beIo.LogFile = ""
+
+	args := ProxyControlStatsArgs{
+		Key:        "__total__",
+		Since:      ctx.String("since"),
+		Resolution: ctx.String("resolution"),
+	}
+	switch {
+	case ctx.NArg() > 1:
+		cli.ShowSubcommandHelpAndExit(ctx, 1)
+	case ctx.NArg() == 1:
+		name := ctx.Args().First()
+		if _, ok := c.config.Applications[name]; !ok {
+			err = fmt.Errorf("application not found: %v", name)
+			return
+		}
+		args.Key = "app|" + name
+	case ctx.String("host") != "":
+		args.Key = "host|" + ctx.String("host")
+	case ctx.String("addr") != "":
+		args.Key = "addr|" + ctx.String("addr")
+	}
+
+	if err = common.DropPrivilegesTo(c.config.RunAs.User, c.config.RunAs.Group); err != nil {
+		err = fmt.Errorf("error dropping root privileges: %v", err)
+		return
+	}
+
+	var stats ProxyControlStats
+	if err = c.config.CallProxyControl("stats", args, &stats); err != nil {
+		err = fmt.Errorf("error calling reverse-proxy: %v", err)
+		return
+	} else if ctx.Bool("json") {
+		err = c.outputProxyControlJson(stats)
+		return
+	}
+
+	layout := "2006-01-02 15:04"
+	if stats.Resolution == "second" {
+		layout = "15:04:05"
+	}
+	c.outputProxyControlTable(func(tw io.Writer) {
+		_, _ = fmt.Fprintf(tw, "TIME\tREQUESTS\t%v\tDELAYED\tLIMITED\tP50 MS\tP90 MS\tP99 MS\tMAX MS\n", strings.ToUpper(strings.Join(statsStatusClasses, "\t")))
+		for _, row := range stats.Rows {
+			writeStatsRow(tw, row.Time.Format(layout), row)
+		}
+		writeStatsRow(tw, "TOTAL", stats.Total)
+	})
+	return
+}
+
+func writeStatsRow(tw io.Writer, label string, row ProxyControlStatsRow) {
+	var classes []string
+	for _, class := range statsStatusClasses {
+		classes = append(classes, fmt.Sprintf("%d", row.Status[class]))
+	}
+	_, _ = fmt.Fprintf(tw, "%v\t%d\t%v\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\n",
+		label, row.Requests, strings.Join(classes, "\t"), row.Delayed, row.Limited,
+		row.P50, row.P90, row.P99, row.Max,
+	)
+}
@@ -84,6 +84,19 @@ func (c *Command) actionStatus(ctx *cli.Context) (err error) {
 		beIo.STDOUT("\n")
 		c.statusDisplayWatchingProxyLimits(proxyLimits)
 	}
+
+	if healthChecks, ee := c.config.CallProxyControlCommand("health-checks"); ee == nil {
+		if phc := parseHealthChecks(healthChecks); len(phc) > 0 {
+			beIo.STDOUT("\n")
+			c.statusDisplayHealthChecks(phc)
+		}
+	}
+
+	var mirrors []ProxyControlMirror
+	if ee := c.config.CallProxyControl("mirrors", nil, &mirrors); ee == nil && len(mirrors) > 0 {
+		beIo.STDOUT("\n")
+		c.statusDisplayMirrors(mirrors)
+	}
 	return
 }
 
@@ -179,17 +192,26 @@ func NewProxyLimitsData() ParsedProxyLimitsData {
 }
 
 type ParsedProxyLimits struct {
-	TotalRequest int64
-	TotalDelayed int64
-
-	Delayed ParsedProxyLimitsData
-	Request ParsedProxyLimitsData
+	TotalRequest  int64
+	TotalDelayed  int64
+	TotalUpgraded int64
+	TotalDenied   int64
+	TotalTooLarge int64
+
+	Delayed  ParsedProxyLimitsData
+	Request  ParsedProxyLimitsData
+	Upgraded ParsedProxyLimitsData
+	Denied   ParsedProxyLimitsData
+	TooLarge ParsedProxyLimitsData
 }
 
 func parseProxyLimits(proxyLimits string) (ppl *ParsedProxyLimits) {
 	ppl = new(ParsedProxyLimits)
 	ppl.Request = NewProxyLimitsData()
 	ppl.Delayed = NewProxyLimitsData()
+	ppl.Upgraded = NewProxyLimitsData()
+	ppl.Denied = NewProxyLimitsData()
+	ppl.TooLarge = NewProxyLimitsData()
 
 	for _, line := range strings.Split(proxyLimits, "\n") {
 		line = strings.TrimSpace(line)
@@ -211,17 +233,28 @@ func parseProxyLimits(proxyLimits string) (ppl *ParsedProxyLimits) {
 				}
 
 			case 3:
-				if names[0] == "delay" {
-					switch names[1] {
-					case "app":
-						ppl.Delayed.Apps[names[2]], _ = strconv.ParseInt(value, 10, 64)
-					case "host":
-						ppl.Delayed.Hosts[names[2]], _ = strconv.ParseInt(value, 10, 64)
-					case "addr":
-						ppl.Delayed.Addrs[names[2]], _ = strconv.ParseInt(value, 10, 64)
-					case "port":
-						ppl.Delayed.Ports[names[2]], _ = strconv.ParseInt(value, 10, 64)
-					}
+				var data ParsedProxyLimitsData
+				switch names[0] {
+				case "delay":
+					data = ppl.Delayed
+				case "upgrade":
+					data = ppl.Upgraded
+				case "denied":
+					data = ppl.Denied
+				case "toolarge":
+					data = ppl.TooLarge
+				default:
+					continue
+				}
+				switch names[1] {
+				case "app":
+					data.Apps[names[2]], _ = strconv.ParseInt(value, 10, 64)
+				case "host":
+					data.Hosts[names[2]], _ = strconv.ParseInt(value, 10, 64)
+				case "addr":
+					data.Addrs[names[2]], _ = strconv.ParseInt(value, 10, 64)
+				case "port":
+					data.Ports[names[2]], _ = strconv.ParseInt(value, 10, 64)
 				}
 
 			default:
@@ -230,6 +263,12 @@ func parseProxyLimits(proxyLimits string) (ppl *ParsedProxyLimits) {
 					ppl.TotalRequest, _ = strconv.ParseInt(value, 10, 64)
 				case "__delay__":
 					ppl.TotalDelayed, _ = strconv.ParseInt(value, 10, 64)
+				case "__upgrade__":
+					ppl.TotalUpgraded, _ = strconv.ParseInt(value, 10, 64)
+				case "__denied__":
+					ppl.TotalDenied, _ = strconv.ParseInt(value, 10, 64)
+				case "__toolarge__":
+					ppl.TotalTooLarge, _ = strconv.ParseInt(value, 10, 64)
 				}
 			}
 		}
@@ -244,23 +283,120 @@ func (c *Command) statusDisplayWatchingProxyLimits(proxyLimits string) {
 
 	ppl := parseProxyLimits(proxyLimits)
 
-	_, _ = tw.Write([]byte("[ PROXY LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\n"))
-	_, _ = tw.Write([]byte(fmt.Sprintf("(total)\t%d\t%d\n", ppl.TotalRequest, ppl.TotalDelayed)))
+	_, _ = tw.Write([]byte("[ PROXY LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\t[ UPGRADED ]\n"))
+	_, _ = tw.Write([]byte(fmt.Sprintf("(total)\t%d\t%d\t%d\n", ppl.TotalRequest, ppl.TotalDelayed, ppl.TotalUpgraded)))
 	if len(ppl.Request.Hosts) > 0 {
-		_, _ = tw.Write([]byte("\t\t\n"))
-		_, _ = tw.Write([]byte("[ HOST LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\n"))
+		_, _ = tw.Write([]byte("\t\t\t\n"))
+		_, _ = tw.Write([]byte("[ HOST LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\t[ UPGRADED ]\n"))
 		for _, key := range maps.SortedKeys(ppl.Request.Hosts) {
-			dHostValue, _ := ppl.Request.Hosts[key]
-			_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%d\t%d\n", key, ppl.Request.Hosts[key], dHostValue)))
+			dHostValue := ppl.Delayed.Hosts[key]
+			uHostValue := ppl.Upgraded.Hosts[key]
+			_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%d\t%d\t%d\n", key, ppl.Request.Hosts[key], dHostValue, uHostValue)))
 		}
 	}
 	if len(ppl.Request.Addrs) > 0 {
-		_, _ = tw.Write([]byte("\t\t\n"))
-		_, _ = tw.Write([]byte("[ ADDR LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\n"))
+		_, _ = tw.Write([]byte("\t\t\t\n"))
+		_, _ = tw.Write([]byte("[ ADDR LIMITS ]\t[ CURRENT ]\t[ DELAYED ]\t[ UPGRADED ]\n"))
 		for _, key := range maps.SortedKeys(ppl.Request.Addrs) {
-			dAddrValue, _ := ppl.Delayed.Addrs[key]
-			_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%d\t%d\n", key, ppl.Request.Addrs[key], dAddrValue)))
+			dAddrValue := ppl.Delayed.Addrs[key]
+			uAddrValue := ppl.Upgraded.Addrs[key]
+			_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%d\t%d\t%d\n", key, ppl.Request.Addrs[key], dAddrValue, uAddrValue)))
+		}
+	}
+	if ppl.TotalDenied > 0 {
+		_, _ = tw.Write([]byte("\t\t\t\n"))
+		_, _ = tw.Write([]byte("[ DENIED ]\t[ RECENT ]\t\t\n"))
+		_, _ = tw.Write([]byte(fmt.Sprintf("(total)\t%d\t\t\n", ppl.TotalDenied)))
+		for _, key := range maps.SortedKeys(ppl.Denied.Apps) {
+			_, _ = tw.Write([]byte(fmt.Sprintf("app: %s\t%d\t\t\n", key, ppl.Denied.Apps[key])))
+		}
+		for _, key := range maps.SortedKeys(ppl.Denied.Addrs) {
+			_, _ = tw.Write([]byte(fmt.Sprintf("addr: %s\t%d\t\t\n", key, ppl.Denied.Addrs[key])))
+		}
+	}
+	if ppl.TotalTooLarge > 0 {
+		_, _ = tw.Write([]byte("\t\t\t\n"))
+		_, _ = tw.Write([]byte("[ TOO LARGE ]\t[ RECENT ]\t\t\n"))
+		_, _ = tw.Write([]byte(fmt.Sprintf("(total)\t%d\t\t\n", ppl.TotalTooLarge)))
+		for _, key := range maps.SortedKeys(ppl.TooLarge.Apps) {
+			_, _ = tw.Write([]byte(fmt.Sprintf("app: %s\t%d\t\t\n", key, ppl.TooLarge.Apps[key])))
+		}
+		for _, key := range maps.SortedKeys(ppl.TooLarge.Addrs) {
+			_, _ = tw.Write([]byte(fmt.Sprintf("addr: %s\t%d\t\t\n", key, ppl.TooLarge.Addrs[key])))
+		}
+	}
+
+	_ = tw.Flush()
+	beIo.STDOUT(buf.String())
+}
+
+type ParsedHealthCheck struct {
+	App       string
+	Hash      string
+	Port      int
+	Healthy   bool
+	Status    int
+	Failures  int
+	LastCheck string
+	LastError string
+}
+
+func parseHealthChecks(healthChecks string) (phc map[int]*ParsedHealthCheck) {
+	phc = make(map[int]*ParsedHealthCheck)
+	for _, line := range strings.Split(healthChecks, "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) == 8 {
+			hc := &ParsedHealthCheck{
+				App:       fields[0],
+				Hash:      fields[1],
+				Healthy:   fields[3] == "healthy",
+				LastCheck: fields[6],
+				LastError: fields[7],
+			}
+			hc.Port, _ = strconv.Atoi(fields[2])
+			hc.Status, _ = strconv.Atoi(fields[4])
+			hc.Failures, _ = strconv.Atoi(fields[5])
+			phc[hc.Port] = hc
+		}
+	}
+	return
+}
+
+func (c *Command) statusDisplayHealthChecks(phc map[int]*ParsedHealthCheck) {
+	buf := bytes.NewBuffer([]byte(""))
+	tw := tabwriter.NewWriter(io.Writer(buf), 8, 2, 2, ' ', tabwriter.FilterHTML)
+
+	_, _ = tw.Write([]byte("[ HEALTH CHECK ]\t[ WORKER ]\t[ PORT ]\t[ STATE ]\t[ STATUS ]\t[ FAILURES ]\t[ CHECKED ]\t[ ERROR ]\n"))
+	for _, port := range maps.SortedNumbers(phc) {
+		hc := phc[port]
+		state := "healthy"
+		if !hc.Healthy {
+			state = "unhealthy"
+		}
+		lastError := hc.LastError
+		if lastError == "" {
+			lastError = "-"
+		}
+		_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\n", hc.App, hc.Hash, hc.Port, state, hc.Status, hc.Failures, hc.LastCheck, lastError)))
+	}
+
+	_ = tw.Flush()
+	beIo.STDOUT(buf.String())
+}
+
+func (c *Command) statusDisplayMirrors(mirrors []ProxyControlMirror) {
+	buf := bytes.NewBuffer([]byte(""))
+	tw := tabwriter.NewWriter(io.Writer(buf), 8, 2, 2, ' ', tabwriter.FilterHTML)
+
+	_, _ = tw.Write([]byte("[ MIRROR ]\t[ SHADOW ]\t[ RATE ]\t[ COMPARED ]\t[ MISMATCHED ]\t[ 5XX ]\t[ LATENCY ]\t[ NOT SENT ]\n"))
+	for _, mirror := range mirrors {
+		latency := "-"
+		if mirror.Compared > 0 {
+			latency = mirror.PrimaryLatency + " / " + mirror.ShadowLatency
 		}
+		_, _ = tw.Write([]byte(fmt.Sprintf("%s\t%s\t%v\t%d of %d\t%d\t%d / %d\t%s\t%d\n",
+			mirror.App, mirror.Slug, mirror.Rate, mirror.Compared, mirror.Mirrored, mirror.StatusMismatches,
+			mirror.PrimaryErrors, mirror.ShadowErrors, latency, mirror.Dropped+mirror.Skipped,
+		)))
 	}
 
 	_ = tw.Flush()
@@ -0,0 +1,179 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	AccessLogFormatNiseroku = "niseroku"
+	AccessLogFormatCombined = "combined"
+	AccessLogFormatJson     = "json"
+	AccessLogFormatTemplate = "template"
+)
+
+type AccessLogConfig struct {
+	Format   string `toml:"format,omitempty"`
+	Template string `toml:"template,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Validate checks the format name and parses the custom template, the format
+// defaults to "template" when only a template is given
+func (c *AccessLogConfig) Validate() (err error) {
+	if c == nil {
+		return
+	}
+	if c.Format == "" && c.Template != "" {
+		c.Format = AccessLogFormatTemplate
+	}
+	switch c.Format {
+	case "", AccessLogFormatNiseroku, AccessLogFormatCombined, AccessLogFormatJson:
+	case AccessLogFormatTemplate:
+		if c.Template == "" {
+			err = fmt.Errorf("access-log.template is required for the template format")
+			return
+		}
+		if c.tmpl, err = template.New("access-log").Parse(c.Template); err != nil {
+			err = fmt.Errorf("error parsing access-log.template: %v", err)
+		}
+	default:
+		err = fmt.Errorf("access-log.format must be one of niseroku, combined, json or template: %q", c.Format)
+	}
+	return
+}
+
+func (c *AccessLogConfig) IsSet() (set bool) {
+	set = c != nil && c.Format != ""
+	return
+}
+
+// Render returns the access log line for the given entry, including the
+// trailing newline
+func (c *AccessLogConfig) Render(entry *AccessLogEntry) (line string) {
+	var format string
+	if c != nil {
+		format = c.Format
+	}
+	switch format {
+	case AccessLogFormatCombined:
+		line = entry.Combined()
+	case AccessLogFormatJson:
+		line = entry.Json()
+	case AccessLogFormatTemplate:
+		var buf bytes.Buffer
+		if err := c.tmpl.Execute(&buf, entry); err != nil {
+			line = fmt.Sprintf("[%v] [%v] access-log template error: %v", entry.App, entry.Time.Format("20060102-150405"), err)
+		} else {
+			line = strings.TrimRight(buf.String(), "\n")
+		}
+	default:
+		line = entry.Niseroku()
+	}
+	line += "\n"
+	return
+}
+
+// AccessLogEntry is the data available to access log formats and templates
+type AccessLogEntry struct {
+	Time       time.Time     `json:"time"`
+	App        string        `json:"app"`
+	Host       string        `json:"host"`
+	RemoteAddr string        `json:"remote_addr"`
+	Method     string        `json:"method"`
+	Uri        string        `json:"uri"`
+	Path       string        `json:"path"`
+	Proto      string        `json:"proto"`
+	Status     int           `json:"status"`
+	Bytes      int64         `json:"bytes"`
+	Latency    time.Duration `json:"-"`
+	Referer    string        `json:"referer,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	RequestId  string        `json:"request_id,omitempty"`
+	Port       int           `json:"upstream_port,omitempty"`
+	Worker     string        `json:"worker,omitempty"`
+	Delay      time.Duration `json:"-"`
+	Cache      string        `json:"cache,omitempty"`
+}
+
+func (e *AccessLogEntry) Niseroku() (line string) {
+	line = fmt.Sprintf(
+		"[%v] [%v] %v - %v - (%d) - %v %v (%v)",
+		e.App,
+		e.Time.Format("20060102-150405"),
+		e.RemoteAddr,
+		e.Host,
+		e.Status,
+		e.Method,
+		e.Path,
+		e.Latency.String(),
+	)
+	return
+}
+
+// Combined returns the entry in the Apache combined log format
+func (e *AccessLogEntry) Combined() (line string) {
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+	referer, userAgent := "-", "-"
+	if e.Referer != "" {
+		referer = e.Referer
+	}
+	if e.UserAgent != "" {
+		userAgent = e.UserAgent
+	}
+	line = fmt.Sprintf(
+		"%s - - [%s] %s %d %s %s %s",
+		e.RemoteAddr,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(e.Method+" "+e.Uri+" "+e.Proto),
+		e.Status,
+		size,
+		strconv.Quote(referer),
+		strconv.Quote(userAgent),
+	)
+	return
+}
+
+// Json returns the entry as a single line JSON object, durations are given in
+// fractional milliseconds
+func (e *AccessLogEntry) Json() (line string) {
+	type entry AccessLogEntry
+	data, err := json.Marshal(struct {
+		*entry
+		LatencyMs float64 `json:"latency_ms"`
+		DelayMs   float64 `json:"rate_limit_delay_ms"`
+	}{
+		entry:     (*entry)(e),
+		LatencyMs: float64(e.Latency.Microseconds()) / 1000,
+		DelayMs:   float64(e.Delay.Microseconds()) / 1000,
+	})
+	if err != nil {
+		line = fmt.Sprintf(`{"app":%q,"error":%q}`, e.App, err.Error())
+		return
+	}
+	line = string(data)
+	return
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type AccessConfig struct {
+	Allow         []string `toml:"allow,omitempty"`
+	Deny          []string `toml:"deny,omitempty"`
+	DeniedStatus  int      `toml:"denied-status,omitempty"`
+	DeniedMessage string   `toml:"denied-message,omitempty"`
+
+	allowNets []*net.IPNet
+	denyNets  []*net.IPNet
+}
+
+// Parse validates the allow and deny lists, plain IP addresses are accepted
+// as single-address networks
+func (a *AccessConfig) Parse() (err error) {
+	if a == nil {
+		return
+	}
+	if a.DeniedStatus != 0 && (a.DeniedStatus < 400 || a.DeniedStatus > 599) {
+		err = fmt.Errorf("access.denied-status must be a 4xx or 5xx status code: %d", a.DeniedStatus)
+		return
+	}
+	if a.allowNets, err = parseAccessNetworks(a.Allow); err != nil {
+		err = fmt.Errorf("access.allow %v", err)
+		return
+	}
+	if a.denyNets, err = parseAccessNetworks(a.Deny); err != nil {
+		err = fmt.Errorf("access.deny %v", err)
+	}
+	return
+}
+
+// Denies returns true if the given address is within any of the deny
+// networks or if there are allow networks and the address is not within any
+// of them
+func (a *AccessConfig) Denies(addr string) (denied bool) {
+	if a == nil || (len(a.allowNets) == 0 && len(a.denyNets) == 0) {
+		return
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		// unknown remote addresses are only allowed without allow networks
+		denied = len(a.allowNets) > 0
+		return
+	}
+	for _, ipNet := range a.denyNets {
+		if denied = ipNet.Contains(ip); denied {
+			return
+		}
+	}
+	if len(a.allowNets) > 0 {
+		denied = true
+		for _, ipNet := range a.allowNets {
+			if ipNet.Contains(ip) {
+				denied = false
+				return
+			}
+		}
+	}
+	return
+}
+
+func (a *AccessConfig) GetDeniedStatus() (status int) {
+	if a != nil && a.DeniedStatus > 0 {
+		status = a.DeniedStatus
+	} else {
+		status = DefaultAccessDeniedStatus
+	}
+	return
+}
+
+func (a *AccessConfig) GetDeniedMessage() (message string) {
+	if a != nil && a.DeniedMessage != "" {
+		message = a.DeniedMessage
+	} else {
+		status := a.GetDeniedStatus()
+		message = fmt.Sprintf("%d - %s", status, http.StatusText(status))
+	}
+	return
+}
+
+func parseAccessNetworks(values []string) (networks []*net.IPNet, err error) {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip == nil {
+				err = fmt.Errorf("invalid address: %q", value)
+				return
+			} else if ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		var ipNet *net.IPNet
+		if _, ipNet, err = net.ParseCIDR(value); err != nil {
+			err = fmt.Errorf("invalid network: %q", value)
+			return
+		}
+		networks = append(networks, ipNet)
+	}
+	return
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+)
+
+type AcmeConfig struct {
+	DirectoryUrl string `toml:"directory-url"`
+	CaRoots      string `toml:"ca-roots,omitempty"`
+	EabKid       string `toml:"eab-kid,omitempty"`
+	EabKey       string `toml:"eab-key,omitempty"`
+}
+
+func (a AcmeConfig) Validate() (err error) {
+	switch {
+	case a.EabKid != "" && a.EabKey == "":
+		err = fmt.Errorf("acme.eab-key setting not found")
+	case a.EabKid == "" && a.EabKey != "":
+		err = fmt.Errorf("acme.eab-kid setting not found")
+	case a.EabKey != "":
+		if _, ee := a.decodeEabKey(); ee != nil {
+			err = fmt.Errorf("acme.eab-key is not base64 encoded: %v", ee)
+		}
+	}
+	return
+}
+
+func (a AcmeConfig) decodeEabKey() (key []byte, err error) {
+	encoded := strings.TrimRight(a.EabKey, "=")
+	if key, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+		key, err = base64.RawStdEncoding.DecodeString(encoded)
+	}
+	return
+}
+
+// ExternalAccountBinding returns the acme EAB credentials, or nil if not
+// configured
+func (a AcmeConfig) ExternalAccountBinding() (eab *acme.ExternalAccountBinding, err error) {
+	if a.EabKid == "" {
+		return
+	}
+	var key []byte
+	if key, err = a.decodeEabKey(); err != nil {
+		return
+	}
+	eab = &acme.ExternalAccountBinding{
+		KID: a.EabKid,
+		Key: key,
+	}
+	return
+}
+
+// Client returns a new acme.Client for the configured directory, trusting the
+// configured ca-roots in addition to the system roots
+func (a AcmeConfig) Client() (client *acme.Client, err error) {
+	client = &acme.Client{
+		DirectoryURL: a.DirectoryUrl,
+	}
+	if a.CaRoots == "" {
+		return
+	}
+	var pool *x509.CertPool
+	if pool, err = x509.SystemCertPool(); err != nil || pool == nil {
+		pool = x509.NewCertPool()
+		err = nil
+	}
+	var data []byte
+	if data, err = os.ReadFile(a.CaRoots); err != nil {
+		err = fmt.Errorf("error reading acme.ca-roots: %v", err)
+		return
+	} else if !pool.AppendCertsFromPEM(data) {
+		err = fmt.Errorf("acme.ca-roots certificates not found: %v", a.CaRoots)
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	client.HTTPClient = &http.Client{Transport: transport}
+	return
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+type CompressionConfig struct {
+	Enable       *bool    `toml:"enable,omitempty"`
+	Encodings    []string `toml:"encodings,omitempty"`
+	ContentTypes []string `toml:"content-types,omitempty"`
+	MinSize      *int64   `toml:"min-size,omitempty"`
+}
+
+func (c *CompressionConfig) Validate() (err error) {
+	if c == nil {
+		return
+	}
+	for _, encoding := range c.Encodings {
+		switch encoding {
+		case EncodingGzip, EncodingZstd:
+		default:
+			err = fmt.Errorf("compression.encodings must be gzip or zstd: %q", encoding)
+			return
+		}
+	}
+	if c.MinSize != nil && *c.MinSize < 0 {
+		err = fmt.Errorf("compression.min-size must not be negative")
+	}
+	return
+}
+
+// Merge returns a copy of these settings with any settings present in the
+// app's [compression] table taking precedence
+func (c CompressionConfig) Merge(app *CompressionConfig) (merged CompressionConfig) {
+	merged = c
+	if app == nil {
+		return
+	}
+	if app.Enable != nil {
+		merged.Enable = app.Enable
+	}
+	if len(app.Encodings) > 0 {
+		merged.Encodings = app.Encodings
+	}
+	if len(app.ContentTypes) > 0 {
+		merged.ContentTypes = app.ContentTypes
+	}
+	if app.MinSize != nil {
+		merged.MinSize = app.MinSize
+	}
+	return
+}
+
+// GetMinSize returns the minimum Content-Length to compress, zero compresses
+// responses of any size
+func (c CompressionConfig) GetMinSize() (size int64) {
+	if c.MinSize != nil {
+		size = *c.MinSize
+	} else {
+		size = DefaultCompressionMinSize
+	}
+	return
+}
+
+func (c CompressionConfig) IsEnabled() (enabled bool) {
+	enabled = c.Enable != nil && *c.Enable
+	return
+}
+
+// AllowsContentType returns true if the media type of the given content type
+// is present in the content-types list, entries ending with "/*" match all
+// subtypes
+func (c CompressionConfig) AllowsContentType(contentType string) (allowed bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return
+	}
+	for _, allow := range c.ContentTypes {
+		if strings.HasSuffix(allow, "/*") {
+			if allowed = strings.HasPrefix(mediaType, strings.TrimSuffix(allow, "*")); allowed {
+				return
+			}
+		} else if allowed = mediaType == allow; allowed {
+			return
+		}
+	}
+	return
+}
@@ -40,6 +40,8 @@ func (c *Config) PrepareDirectories() (err error) {
 		c.Paths.Etc,
 		c.Paths.EtcApps,
 		c.Paths.EtcUsers,
+		c.Paths.EtcErrorPages,
+		c.Paths.EtcAuth,
 		c.Paths.Tmp,
 		c.Paths.TmpRun,
 		c.Paths.TmpClone,
@@ -49,6 +51,7 @@ func (c *Config) PrepareDirectories() (err error) {
 		c.Paths.VarSlugs,
 		c.Paths.VarSettings,
 		c.Paths.VarCache,
+		c.Paths.VarProxyCache,
 		c.Paths.VarRepos,
 		c.Paths.VarAptRoot,
 	); err != nil {
@@ -15,8 +15,13 @@
 package niseroku
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"strings"
+	"time"
 )
 
 const (
@@ -33,11 +38,8 @@ func (c *Config) WriteProxyControl(conn net.Conn, name string, argv ...string) (
 	if _, err = conn.Write([]byte(command)); err != nil {
 		return
 	}
-	var data []byte
-	buf := make([]byte, ProxyControlResponseMaxSize)
-	if n, ee := conn.Read(buf); ee == nil {
-		data = append(data, buf[:n]...)
-	}
+	// the reverse-proxy closes the connection after writing the response
+	data, _ := io.ReadAll(io.LimitReader(conn, ProxyControlResponseMaxSize))
 	response = string(data)
 	return
 }
@@ -53,3 +55,223 @@ func (c *Config) CallProxyControlCommand(name string, argv ...string) (response
 	response, err = c.WriteProxyControl(conn, name, argv...)
 	return
 }
+
+// NotifyProxyMetric sends a metrics-event to the reverse-proxy, errors are
+// ignored as the reverse-proxy may not be running
+func (c *Config) NotifyProxyMetric(event string, argv ...string) {
+	_, _ = c.CallProxyControlCommand("metrics-event", append([]string{event}, argv...)...)
+}
+
+// ProxyControlProtocolVersion is the version of the newline-delimited JSON
+// control socket protocol; requests are JSON objects starting with "{" and
+// each request receives one JSON response line
+const ProxyControlProtocolVersion = 1
+
+type ProxyControlRequest struct {
+	Version int             `json:"version"`
+	Id      string          `json:"id,omitempty"`
+	Command string          `json:"command"`
+	Args    json.RawMessage `json:"args,omitempty"`
+}
+
+type ProxyControlResponse struct {
+	Version int             `json:"version"`
+	Id      string          `json:"id,omitempty"`
+	Ok      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type ProxyControlInfo struct {
+	Version  int      `json:"version"`
+	Commands []string `json:"commands"`
+}
+
+type ProxyControlAppArgs struct {
+	App string `json:"app"`
+}
+
+type ProxyControlMaintenanceArgs struct {
+	App    string `json:"app"`
+	Enable bool   `json:"enable"`
+}
+
+type ProxyControlDrainArgs struct {
+	App   string `json:"app"`
+	Port  int    `json:"port"`
+	Drain bool   `json:"drain"`
+}
+
+type ProxyControlTextArgs struct {
+	Argv []string `json:"argv,omitempty"`
+}
+
+type ProxyControlApp struct {
+	Name        string   `json:"name"`
+	Domains     []string `json:"domains"`
+	Maintenance bool     `json:"maintenance"`
+	ThisSlug    string   `json:"this-slug,omitempty"`
+	NextSlug    string   `json:"next-slug,omitempty"`
+	LivePorts   []int    `json:"live-ports"`
+}
+
+type ProxyControlRoute struct {
+	Host   string `json:"host"`
+	Prefix string `json:"prefix"`
+	App    string `json:"app"`
+}
+
+type ProxyControlRedirect struct {
+	FromHost string `json:"from-host"`
+	ToHost   string `json:"to-host"`
+	Status   int    `json:"status"`
+	App      string `json:"app"`
+}
+
+type ProxyControlRoutes struct {
+	Routes    []ProxyControlRoute    `json:"routes"`
+	Redirects []ProxyControlRedirect `json:"redirects"`
+}
+
+type ProxyControlWorker struct {
+	App         string `json:"app"`
+	Slug        string `json:"slug"`
+	Hash        string `json:"hash"`
+	Port        int    `json:"port"`
+	Running     bool   `json:"running"`
+	Ready       bool   `json:"ready"`
+	Healthy     bool   `json:"healthy"`
+	Draining    bool   `json:"draining"`
+	CircuitOpen bool   `json:"circuit-open"`
+	InFlight    int64  `json:"in-flight"`
+}
+
+const (
+	CanaryActionStart   = "start"
+	CanaryActionStatus  = "status"
+	CanaryActionPercent = "percent"
+	CanaryActionPromote = "promote"
+	CanaryActionAbort   = "abort"
+	CanaryActionEnd     = "end"
+
+	CanaryDecisionPromote = "promote"
+	CanaryDecisionAbort   = "abort"
+)
+
+type ProxyControlCanaryArgs struct {
+	App     string `json:"app"`
+	Action  string `json:"action"`
+	Slug    string `json:"slug,omitempty"`
+	Percent int    `json:"percent,omitempty"`
+}
+
+type ProxyControlCanary struct {
+	App              string    `json:"app"`
+	Slug             string    `json:"slug"`
+	Active           bool      `json:"active"`
+	Percent          int       `json:"percent"`
+	Started          time.Time `json:"started"`
+	Duration         string    `json:"duration"`
+	BaselineRequests int64     `json:"baseline-requests"`
+	BaselineErrors   int64     `json:"baseline-errors"`
+	CanaryRequests   int64     `json:"canary-requests"`
+	CanaryErrors     int64     `json:"canary-errors"`
+	Decision         string    `json:"decision,omitempty"`
+	Reason           string    `json:"reason,omitempty"`
+}
+
+type ProxyControlMirror struct {
+	App              string                       `json:"app"`
+	Slug             string                       `json:"slug"`
+	Rate             float64                      `json:"rate"`
+	InFlight         int                          `json:"in-flight"`
+	Mirrored         int64                        `json:"mirrored"`
+	Dropped          int64                        `json:"dropped"`
+	Skipped          int64                        `json:"skipped"`
+	Errors           int64                        `json:"errors"`
+	Compared         int64                        `json:"compared"`
+	StatusMatches    int64                        `json:"status-matches"`
+	StatusMismatches int64                        `json:"status-mismatches"`
+	PrimaryErrors    int64                        `json:"primary-errors"`
+	ShadowErrors     int64                        `json:"shadow-errors"`
+	PrimaryLatency   string                       `json:"primary-latency"`
+	ShadowLatency    string                       `json:"shadow-latency"`
+	Mismatches       []ProxyControlMirrorMismatch `json:"mismatches,omitempty"`
+}
+
+type ProxyControlMirrorMismatch struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Primary int       `json:"primary"`
+	Shadow  int       `json:"shadow"`
+}
+
+type ProxyControlStatsArgs struct {
+	// Key is a stats series, one of: __total__, app|<name>, host|<domain> or
+	// addr|<address>
+	Key string `json:"key"`
+	// Since is how far back to report, in the Go time.Duration format
+	Since string `json:"since,omitempty"`
+	// Resolution is either "second" or "minute", defaulting to the finest
+	// resolution covering all of Since
+	Resolution string `json:"resolution,omitempty"`
+}
+
+type ProxyControlStats struct {
+	Key        string                 `json:"key"`
+	Resolution string                 `json:"resolution"`
+	Since      time.Time              `json:"since"`
+	Total      ProxyControlStatsRow   `json:"total"`
+	Rows       []ProxyControlStatsRow `json:"rows"`
+}
+
+type ProxyControlStatsRow struct {
+	Time     time.Time        `json:"time"`
+	Requests int64            `json:"requests"`
+	Status   map[string]int64 `json:"status"`
+	Delayed  int64            `json:"delayed"`
+	Limited  int64            `json:"limited"`
+	P50      float64          `json:"p50-ms"`
+	P90      float64          `json:"p90-ms"`
+	P99      float64          `json:"p99-ms"`
+	Max      float64          `json:"max-ms"`
+}
+
+// CallProxyControl sends one JSON protocol request to the reverse-proxy,
+// decoding the response data into the given data pointer if not nil
+func (c *Config) CallProxyControl(command string, args interface{}, data interface{}) (err error) {
+	var conn net.Conn
+	if conn, err = c.DialProxyControl(); err != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	request := ProxyControlRequest{
+		Version: ProxyControlProtocolVersion,
+		Command: command,
+	}
+	if args != nil {
+		if request.Args, err = json.Marshal(args); err != nil {
+			return
+		}
+	}
+	if err = json.NewEncoder(conn).Encode(request); err != nil {
+		return
+	}
+
+	var response ProxyControlResponse
+	if err = json.NewDecoder(conn).Decode(&response); err != nil {
+		err = fmt.Errorf("error decoding response: %v", err)
+		return
+	} else if !response.Ok {
+		err = errors.New(response.Error)
+		return
+	}
+	if data != nil && len(response.Data) > 0 {
+		err = json.Unmarshal(response.Data, data)
+	}
+	return
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration `toml:"read-header-timeout,omitempty"`
+	ReadTimeout       time.Duration `toml:"read-timeout,omitempty"`
+	WriteTimeout      time.Duration `toml:"write-timeout,omitempty"`
+	IdleTimeout       time.Duration `toml:"idle-timeout,omitempty"`
+	MaxHeaderBytes    int           `toml:"max-header-bytes,omitempty"`
+	MaxBodySize       int64         `toml:"max-body-size,omitempty"`
+
+	ProxyProtocol  bool     `toml:"proxy-protocol,omitempty"`
+	TrustedProxies []string `toml:"trusted-proxies,omitempty"`
+
+	trustedNets []*net.IPNet
+}
+
+func (c *ServerConfig) Validate() (err error) {
+	if c.trustedNets, err = parseAccessNetworks(c.TrustedProxies); err != nil {
+		err = fmt.Errorf("server.trusted-proxies %v", err)
+		return
+	}
+	switch {
+	case c.ProxyProtocol && len(c.TrustedProxies) == 0:
+		err = fmt.Errorf("server.proxy-protocol requires server.trusted-proxies")
+	case c.ReadHeaderTimeout < 0:
+		err = fmt.Errorf("server.read-header-timeout must not be negative")
+	case c.ReadTimeout < 0:
+		err = fmt.Errorf("server.read-timeout must not be negative")
+	case c.WriteTimeout < 0:
+		err = fmt.Errorf("server.write-timeout must not be negative")
+	case c.IdleTimeout < 0:
+		err = fmt.Errorf("server.idle-timeout must not be negative")
+	case c.MaxHeaderBytes < 0:
+		err = fmt.Errorf("server.max-header-bytes must not be negative")
+	case c.MaxBodySize < 0:
+		err = fmt.Errorf("server.max-body-size must not be negative")
+	}
+	return
+}
+
+// IsTrustedProxy returns true if the given address is within any of the
+// trusted-proxies networks
+func (c ServerConfig) IsTrustedProxy(addr string) (trusted bool) {
+	if len(c.trustedNets) == 0 {
+		return
+	}
+	if ip := net.ParseIP(addr); ip != nil {
+		for _, ipNet := range c.trustedNets {
+			if trusted = ipNet.Contains(ip); trusted {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Apply sets the timeouts and header limit of the given server, zero read and
+// write timeouts are left disabled so that long uploads, downloads and
+// upgraded connections are not interrupted
+func (c ServerConfig) Apply(server *http.Server) {
+	server.ReadHeaderTimeout = c.ReadHeaderTimeout
+	server.ReadTimeout = c.ReadTimeout
+	server.WriteTimeout = c.WriteTimeout
+	server.IdleTimeout = c.IdleTimeout
+	server.MaxHeaderBytes = c.MaxHeaderBytes
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+)
+
+type StatsConfig struct {
+	Seconds      int `toml:"seconds,omitempty"`
+	Minutes      int `toml:"minutes,omitempty"`
+	MaxAddresses int `toml:"max-addresses,omitempty"`
+}
+
+func (c StatsConfig) Validate() (err error) {
+	switch {
+	case c.Seconds < 0:
+		err = fmt.Errorf("stats.seconds must not be negative")
+	case c.Minutes < 0:
+		err = fmt.Errorf("stats.minutes must not be negative")
+	case c.MaxAddresses < 0:
+		err = fmt.Errorf("stats.max-addresses must not be negative")
+	}
+	return
+}
@@ -152,6 +152,14 @@ var configTomlComments = TomlComments{
 			"",
 		},
 	},
+	{
+		Statement: "upgrade-idle",
+		Lines: []string{
+			": upgrade-idle      (time.Duration)",
+			":     * maximum idle time for upgraded (websocket) connections",
+			"",
+		},
+	},
 	{
 		Statement: "[proxy-limit]",
 		Lines: []string{
@@ -217,6 +225,266 @@ var configTomlComments = TomlComments{
 			"",
 		},
 	},
+	{
+		Statement: "[access]",
+		Lines: []string{
+			": [access]          (section)",
+			":     * reverse-proxy client network access lists for all apps",
+			":     * apps may also have their own [access] section",
+			":     * requires niseroku-proxy reload or restart if changed",
+			"",
+		},
+	},
+	{
+		Statement: "allow",
+		Lines: []string{
+			": allow (string...) - only allow clients within these CIDR networks",
+			"",
+		},
+	},
+	{
+		Statement: "deny",
+		Lines: []string{
+			": deny (string...) - deny clients within these CIDR networks",
+			"",
+		},
+	},
+	{
+		Statement: "denied-status",
+		Lines: []string{
+			": denied-status (int) - response status code for denied requests",
+			"",
+		},
+	},
+	{
+		Statement: "denied-message",
+		Lines: []string{
+			": denied-message (string) - response body for denied requests",
+			"",
+		},
+	},
+	{
+		Statement: "[acme]",
+		Lines: []string{
+			": [acme]            (section)",
+			":     * let's encrypt (or other acme ca) certificate settings",
+			":     * requires niseroku-proxy restart if changed",
+			"",
+		},
+	},
+	{
+		Statement: "directory-url",
+		Lines: []string{
+			": directory-url (url) - acme directory, defaults to let's encrypt production",
+			"",
+		},
+	},
+	{
+		Statement: "ca-roots",
+		Lines: []string{
+			": ca-roots (path) - PEM file of extra CA roots to trust for the acme directory",
+			"",
+		},
+	},
+	{
+		Statement: "eab-kid",
+		Lines: []string{
+			": eab-kid (string) - external account binding key identifier",
+			"",
+		},
+	},
+	{
+		Statement: "eab-key",
+		Lines: []string{
+			": eab-key (string) - external account binding base64url encoded HMAC key",
+			"",
+		},
+	},
+	{
+		Statement: "[compression]",
+		Lines: []string{
+			": [compression]     (section)",
+			":     * reverse-proxy response compression for all apps",
+			":     * apps may also have their own [compression] section",
+			":     * requires niseroku-proxy reload or restart if changed",
+			"",
+		},
+	},
+	{
+		Statement: "enable",
+		Lines: []string{
+			": enable (bool) - compress responses not already encoded by the origin",
+			"",
+		},
+	},
+	{
+		Statement: "encodings",
+		Lines: []string{
+			": encodings (string...) - zstd and/or gzip, in order of preference",
+			"",
+		},
+	},
+	{
+		Statement: "content-types",
+		Lines: []string{
+			": content-types (string...) - media types to compress, ie: text/* or application/json",
+			"",
+		},
+	},
+	{
+		Statement: "min-size",
+		Lines: []string{
+			": min-size (int) - minimum Content-Length to compress (default 1024, 0 for any size),",
+			":     unknown lengths are always compressed",
+			"",
+		},
+	},
+	{
+		Statement: "[access-log]",
+		Lines: []string{
+			": [access-log]      (section)",
+			":     * app access log line format for all apps",
+			":     * apps may also have their own [access-log] section",
+			":     * requires niseroku-proxy reload or restart if changed",
+			"",
+		},
+	},
+	{
+		Statement: "format",
+		Lines: []string{
+			": format (string) - niseroku (default), combined, json or template",
+			"",
+		},
+	},
+	{
+		Statement: "template",
+		Lines: []string{
+			": template (string) - text/template using the access log entry fields,",
+			":     ie: {{.Time}} {{.RemoteAddr}} {{.Status}} {{.Bytes}} {{.Latency}} {{.Worker}}",
+			"",
+		},
+	},
+	{
+		Statement: "[metrics]",
+		Lines: []string{
+			": [metrics]         (section)",
+			":     * prometheus metrics, also available with the metrics control command",
+			":     * requires niseroku-proxy restart if changed",
+			"",
+		},
+	},
+	{
+		Statement: "listen",
+		Lines: []string{
+			": listen (host:port) - serve /metrics on this local address, ie: 127.0.0.1:9180",
+			"",
+		},
+	},
+	{
+		Statement: "[stats]",
+		Lines: []string{
+			": [stats]           (section)",
+			":     * reverse-proxy request history, see: niseroku stats",
+			":     * requires niseroku-proxy restart if changed, history kept with",
+			":       different settings is discarded",
+			"",
+		},
+	},
+	{
+		Statement: "seconds",
+		Lines: []string{
+			": seconds (int) - per-second history kept (default 600)",
+			"",
+		},
+	},
+	{
+		Statement: "minutes",
+		Lines: []string{
+			": minutes (int) - per-minute history kept (default 1440)",
+			"",
+		},
+	},
+	{
+		Statement: "max-addresses",
+		Lines: []string{
+			": max-addresses (int) - remote addresses tracked across all of the",
+			":     per-second history, any more share one series (default 4096);",
+			":     remote addresses are not kept in the per-minute history",
+			"",
+		},
+	},
+	{
+		Statement: "[server]",
+		Lines: []string{
+			": [server]          (section)",
+			":     * reverse-proxy http and https listener limits",
+			":     * requires niseroku-proxy restart if changed, except max-body-size",
+			":       and trusted-proxies",
+			"",
+		},
+	},
+	{
+		Statement: "read-header-timeout",
+		Lines: []string{
+			": read-header-timeout (time.Duration) - time allowed to read request headers (default 10s)",
+			"",
+		},
+	},
+	{
+		Statement: "read-timeout",
+		Lines: []string{
+			": read-timeout (time.Duration) - time allowed to read entire requests, including",
+			":     the body, unset or zero to not limit large uploads",
+			"",
+		},
+	},
+	{
+		Statement: "write-timeout",
+		Lines: []string{
+			": write-timeout (time.Duration) - time allowed to write responses, unset or zero",
+			":     to not limit streaming responses and large downloads",
+			"",
+		},
+	},
+	{
+		Statement: "idle-timeout",
+		Lines: []string{
+			": idle-timeout (time.Duration) - time to keep idle keep-alive connections (default 2m)",
+			"",
+		},
+	},
+	{
+		Statement: "max-header-bytes",
+		Lines: []string{
+			": max-header-bytes (int) - maximum size of request headers (default 1MiB)",
+			"",
+		},
+	},
+	{
+		Statement: "max-body-size",
+		Lines: []string{
+			": max-body-size (int) - maximum size of request bodies, unset or zero for no",
+			":     limit, apps may set their own [proxy] max-body-size",
+			"",
+		},
+	},
+	{
+		Statement: "proxy-protocol",
+		Lines: []string{
+			": proxy-protocol (bool) - accept PROXY protocol v1 and v2 headers on the http",
+			":     and https listeners, only from trusted-proxies which must be set",
+			"",
+		},
+	},
+	{
+		Statement: "trusted-proxies",
+		Lines: []string{
+			": trusted-proxies (string...) - CIDR networks of load balancers which are",
+			":     trusted to send PROXY protocol headers and the X-Forwarded-For,",
+			":     X-Forwarded-Proto, X-Forwarded-Host and Forwarded request headers",
+			"",
+		},
+	},
 	{
 		Statement: "[run-as]",
 		Lines: []string{
@@ -18,12 +18,15 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/go-corelibs/path"
 )
@@ -43,12 +46,71 @@ var (
 	DefaultSlugStartupTimeout   = 5 * time.Minute
 	DefaultOriginRequestTimeout = time.Minute
 	DefaultReadyIntervalTimeout = time.Second
+	DefaultUpgradeIdleTimeout   = 5 * time.Minute
+
+	DefaultProxyFlushInterval = 100 * time.Millisecond
+
+	DefaultServerReadHeaderTimeout = 10 * time.Second
+	DefaultServerIdleTimeout       = 2 * time.Minute
+	DefaultServerMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+
+	DefaultHealthCheckInterval           = 10 * time.Second
+	DefaultHealthCheckTimeout            = 2 * time.Second
+	DefaultHealthCheckHealthyThreshold   = 2
+	DefaultHealthCheckUnhealthyThreshold = 3
+
+	DefaultRetryAttempts         = 3
+	DefaultRetryBudget           = 0.2
+	DefaultRetryBudgetMinRetries = 10.0
+	DefaultBreakerFailures       = 5
+	DefaultBreakerCooldown       = 10 * time.Second
+
+	DefaultCanaryDuration         = 10 * time.Minute
+	DefaultCanaryMinRequests      = 100
+	DefaultCanaryMaxErrorRate     = 0.05
+	DefaultCanaryPollInterval     = time.Second
+	DefaultCanaryGracePeriod      = 5 * time.Minute
+	DefaultCanaryMaxControlErrors = 30
+
+	DefaultMirrorMaxConcurrent = 10
+	DefaultMirrorMaxBodySize   = int64(1 << 20)
+	DefaultMirrorMaxMismatches = 10
+	DefaultMirrorMethods       = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+	DefaultStatsSeconds      = 600
+	DefaultStatsMinutes      = 1440
+	DefaultStatsMaxAddresses = 4096
+	DefaultStatsSince        = time.Hour
 
 	DefaultRateLimitTTL        time.Duration = 8760 * time.Hour
 	DefaultRateLimitMax        float64       = 150.0
 	DefaultRateLimitBurst      int           = 150
 	DefaultRateLimitMaxDelay   time.Duration = 2 * time.Second
 	DefaultRateLimitDelayScale int           = 10
+
+	DefaultErrorPageRetryAfter = 5 * time.Minute
+
+	DefaultAuthRealm = "Restricted"
+
+	DefaultCacheMaxSize      int64 = 256 * 1024 * 1024
+	DefaultCacheMemorySize   int64 = 32 * 1024 * 1024
+	DefaultCacheMaxEntrySize int64 = 8 * 1024 * 1024
+
+	DefaultCompressionMinSize      int64 = 1024
+	DefaultCompressionEncodings          = []string{EncodingZstd, EncodingGzip}
+	DefaultCompressionContentTypes       = []string{
+		"text/*",
+		"application/javascript",
+		"application/json",
+		"application/manifest+json",
+		"application/xml",
+		"application/rss+xml",
+		"application/atom+xml",
+		"image/svg+xml",
+	}
+
+	DefaultAccessDeniedStatus = http.StatusForbidden
+	DefaultDeniedStatLifetime = 10 * time.Second
 )
 
 type Config struct {
@@ -68,8 +130,22 @@ type Config struct {
 
 	Timeouts TimeoutsConfig `toml:"timeouts"`
 
+	Server ServerConfig `toml:"server"`
+
 	ProxyLimit RateLimit `toml:"proxy-limit"`
 
+	Access AccessConfig `toml:"access"`
+
+	Acme AcmeConfig `toml:"acme"`
+
+	Compression CompressionConfig `toml:"compression"`
+
+	AccessLogFormat AccessLogConfig `toml:"access-log"`
+
+	Metrics MetricsConfig `toml:"metrics"`
+
+	Stats StatsConfig `toml:"stats"`
+
 	Ports PortsConfig `toml:"ports"`
 	RunAs RunAsConfig `toml:"run-as"`
 	Paths PathsConfig `toml:"paths"`
@@ -83,6 +159,9 @@ type Config struct {
 	ReservePorts map[int]*Application    `toml:"-"`
 	DomainLookup map[string]*Application `toml:"-"`
 
+	RedirectLookup map[string]*AppRedirect `toml:"-"`
+	RouteLookup    map[string][]*AppRoute  `toml:"-"`
+
 	tomlMetaData toml.MetaData
 	tomlComments TomlComments
 	sync.RWMutex
@@ -97,6 +176,20 @@ type TimeoutsConfig struct {
 	SlugStartup   time.Duration `toml:"slug-startup"`
 	ReadyInterval time.Duration `toml:"ready-interval"`
 	OriginRequest time.Duration `toml:"origin-request"`
+	UpgradeIdle   time.Duration `toml:"upgrade-idle"`
+}
+
+type MetricsConfig struct {
+	Listen string `toml:"listen,omitempty"`
+}
+
+func (c MetricsConfig) Validate() (err error) {
+	if c.Listen != "" {
+		if _, _, err = net.SplitHostPort(c.Listen); err != nil {
+			err = fmt.Errorf("metrics.listen must be a host:port address: %v", err)
+		}
+	}
+	return
 }
 
 type RunAsConfig struct {
@@ -128,17 +221,21 @@ type PathsConfig struct {
 	Var string `toml:"var"`
 	Tmp string `toml:"tmp"`
 
-	EtcApps     string `toml:"-"` // EtcApps contains all app.toml files
-	EtcUsers    string `toml:"-"` // EtcUsers contains all the user.toml files
-	TmpRun      string `toml:"-"` // TmpRun is used when running enjenv slugs
-	TmpClone    string `toml:"-"` // TmpClone is used during deployment for buildpack clones
-	TmpBuild    string `toml:"-"` // TmpBuild is used during deployment for app build directories
-	VarLogs     string `toml:"-"` // VarLogs is where slug log files are stored
-	VarRepos    string `toml:"-"` // VarRepos is where git repos are stored
-	VarCache    string `toml:"-"` // VarCache is where build cache directories as stored
-	VarSlugs    string `toml:"-"` // VarSlugs is where slug archives are stored
-	VarAptRoot  string `toml:"-"` // VarAptRoot is the path to the apt-repository and apt-archives shared directories
-	VarSettings string `toml:"-"` // VarSettings is where slug env directories are stored
+	EtcApps  string `toml:"-"` // EtcApps contains all app.toml files
+	EtcUsers string `toml:"-"` // EtcUsers contains all the user.toml files
+
+	EtcErrorPages string `toml:"-"` // EtcErrorPages contains per-app error page directories
+	EtcAuth       string `toml:"-"` // EtcAuth contains htpasswd files for app basic-auth
+	TmpRun        string `toml:"-"` // TmpRun is used when running enjenv slugs
+	TmpClone      string `toml:"-"` // TmpClone is used during deployment for buildpack clones
+	TmpBuild      string `toml:"-"` // TmpBuild is used during deployment for app build directories
+	VarLogs       string `toml:"-"` // VarLogs is where slug log files are stored
+	VarRepos      string `toml:"-"` // VarRepos is where git repos are stored
+	VarCache      string `toml:"-"` // VarCache is where build cache directories as stored
+	VarProxyCache string `toml:"-"` // VarProxyCache is where reverse-proxy response cache overflow is stored
+	VarSlugs      string `toml:"-"` // VarSlugs is where slug archives are stored
+	VarAptRoot    string `toml:"-"` // VarAptRoot is the path to the apt-repository and apt-archives shared directories
+	VarSettings   string `toml:"-"` // VarSettings is where slug env directories are stored
 
 	AptSecrets string `toml:"-"` // AptSecrets is where gpg signing keys are stored
 
@@ -146,6 +243,7 @@ type PathsConfig struct {
 	RepoPidFile string `toml:"-"` // RepoPidFile is the path for the git-repository service process ID file
 
 	ProxyPidFile string `toml:"-"` // ProxyPidFile is the path for the reverse-proxy service process ID file
+	ProxyStats   string `toml:"-"` // ProxyStats is where the reverse-proxy request history is saved on shutdown
 	ProxySecrets string `toml:"-"` // ProxySecrets is where ssl-certs are stored
 	ProxyRpcSock string `toml:"-"` // ProxyRpcSock is the path for local unix socket file
 }
@@ -242,6 +340,8 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 
 	appsPath := cfg.Paths.Etc + "/apps.d"
 	usersPath := cfg.Paths.Etc + "/users.d"
+	errorPagesPath := cfg.Paths.Etc + "/error-pages.d"
+	authPath := cfg.Paths.Etc + "/auth.d"
 	aptSecrets := cfg.Paths.Etc + "/secrets.apt.d"
 	proxySecrets := cfg.Paths.Etc + "/secrets.proxy.d"
 	// etcRepoPath := cfg.Paths.Etc + "/repos.d"
@@ -253,6 +353,7 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 	tmpBuild := cfg.Paths.Tmp + "/builds.d"
 	varLogs := cfg.Paths.Var + "/logs.d"
 	varCache := cfg.Paths.Var + "/caches.d"
+	varProxyCache := cfg.Paths.Var + "/proxy-cache.d"
 	varSlugs := cfg.Paths.Var + "/slugs.d"
 	varSettings := cfg.Paths.Var + "/settings.d"
 
@@ -267,6 +368,7 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 
 	repoPidFile := cfg.Paths.Var + "/git-repository.pid"
 	proxyPidFile := cfg.Paths.Var + "/reverse-proxy.pid"
+	proxyStats := cfg.Paths.Var + "/reverse-proxy.stats"
 	proxyRpcSock := cfg.Paths.Var + "/reverse-proxy.sock"
 
 	var needRootUser bool
@@ -303,7 +405,7 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 		runAsGroup = runAsUser
 	}
 
-	var slugStartupTimeout, originRequestTimeout, readyIntervalTimeout time.Duration
+	var slugStartupTimeout, originRequestTimeout, readyIntervalTimeout, upgradeIdleTimeout time.Duration
 	if cfg.Timeouts.SlugStartup > 0 {
 		slugStartupTimeout = cfg.Timeouts.SlugStartup
 	} else {
@@ -319,6 +421,11 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 	} else {
 		readyIntervalTimeout = DefaultReadyIntervalTimeout
 	}
+	if cfg.Timeouts.UpgradeIdle > 0 {
+		upgradeIdleTimeout = cfg.Timeouts.UpgradeIdle
+	} else {
+		upgradeIdleTimeout = DefaultUpgradeIdleTimeout
+	}
 
 	var appEndPort, appStartPort int
 	if cfg.Ports.AppEnd > 0 {
@@ -373,6 +480,7 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 			SlugStartup:   slugStartupTimeout,
 			ReadyInterval: readyIntervalTimeout,
 			OriginRequest: originRequestTimeout,
+			UpgradeIdle:   upgradeIdleTimeout,
 		},
 		RunAs: RunAsConfig{
 			User:  runAsUser,
@@ -395,31 +503,92 @@ func validateConfig(niserokuConfig string, cfg *Config) (config *Config, err err
 			LogDelayed: cfg.ProxyLimit.LogDelayed,
 			LogLimited: cfg.ProxyLimit.LogLimited,
 		},
+		Access: AccessConfig{
+			Allow:         cfg.Access.Allow,
+			Deny:          cfg.Access.Deny,
+			DeniedStatus:  CheckAB(cfg.Access.DeniedStatus, DefaultAccessDeniedStatus, cfg.Access.DeniedStatus > 0),
+			DeniedMessage: cfg.Access.DeniedMessage,
+		},
+		Acme: AcmeConfig{
+			DirectoryUrl: CheckAB(cfg.Acme.DirectoryUrl, autocert.DefaultACMEDirectory, cfg.Acme.DirectoryUrl != ""),
+			CaRoots:      cfg.Acme.CaRoots,
+			EabKid:       cfg.Acme.EabKid,
+			EabKey:       cfg.Acme.EabKey,
+		},
+		Compression: CompressionConfig{
+			Enable:       cfg.Compression.Enable,
+			Encodings:    CheckAB(cfg.Compression.Encodings, DefaultCompressionEncodings, len(cfg.Compression.Encodings) > 0),
+			ContentTypes: CheckAB(cfg.Compression.ContentTypes, DefaultCompressionContentTypes, len(cfg.Compression.ContentTypes) > 0),
+			MinSize:      cfg.Compression.MinSize,
+		},
+		AccessLogFormat: AccessLogConfig{
+			Format:   CheckAB(cfg.AccessLogFormat.Format, AccessLogFormatNiseroku, cfg.AccessLogFormat.Format != "" || cfg.AccessLogFormat.Template != ""),
+			Template: cfg.AccessLogFormat.Template,
+		},
+		Metrics: cfg.Metrics,
+		Stats: StatsConfig{
+			Seconds:      CheckAB(cfg.Stats.Seconds, DefaultStatsSeconds, cfg.Stats.Seconds > 0),
+			Minutes:      CheckAB(cfg.Stats.Minutes, DefaultStatsMinutes, cfg.Stats.Minutes > 0),
+			MaxAddresses: CheckAB(cfg.Stats.MaxAddresses, DefaultStatsMaxAddresses, cfg.Stats.MaxAddresses > 0),
+		},
+		Server: ServerConfig{
+			ReadHeaderTimeout: CheckAB(cfg.Server.ReadHeaderTimeout, DefaultServerReadHeaderTimeout, cfg.Server.ReadHeaderTimeout > 0),
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       CheckAB(cfg.Server.IdleTimeout, DefaultServerIdleTimeout, cfg.Server.IdleTimeout > 0),
+			MaxHeaderBytes:    CheckAB(cfg.Server.MaxHeaderBytes, DefaultServerMaxHeaderBytes, cfg.Server.MaxHeaderBytes > 0),
+			MaxBodySize:       cfg.Server.MaxBodySize,
+			ProxyProtocol:     cfg.Server.ProxyProtocol,
+			TrustedProxies:    cfg.Server.TrustedProxies,
+		},
 		Paths: PathsConfig{
-			Etc:          cfg.Paths.Etc,
-			Var:          cfg.Paths.Var,
-			Tmp:          cfg.Paths.Tmp,
-			EtcApps:      appsPath,
-			EtcUsers:     usersPath,
-			TmpRun:       tmpRun,
-			TmpClone:     tmpClone,
-			TmpBuild:     tmpBuild,
-			VarLogs:      varLogs,
-			VarRepos:     varReposPath,
-			VarCache:     varCache,
-			VarSlugs:     varSlugs,
-			VarSettings:  varSettings,
-			AptSecrets:   aptSecrets,
-			RepoSecrets:  repoSecrets,
-			VarAptRoot:   repoAptPath,
-			ProxySecrets: proxySecrets,
-			ProxyRpcSock: proxyRpcSock,
-			RepoPidFile:  repoPidFile,
-			ProxyPidFile: proxyPidFile,
+			Etc:           cfg.Paths.Etc,
+			Var:           cfg.Paths.Var,
+			Tmp:           cfg.Paths.Tmp,
+			EtcApps:       appsPath,
+			EtcUsers:      usersPath,
+			EtcErrorPages: errorPagesPath,
+			EtcAuth:       authPath,
+			TmpRun:        tmpRun,
+			TmpClone:      tmpClone,
+			TmpBuild:      tmpBuild,
+			VarLogs:       varLogs,
+			VarRepos:      varReposPath,
+			VarCache:      varCache,
+			VarProxyCache: varProxyCache,
+			VarSlugs:      varSlugs,
+			VarSettings:   varSettings,
+			AptSecrets:    aptSecrets,
+			RepoSecrets:   repoSecrets,
+			VarAptRoot:    repoAptPath,
+			ProxySecrets:  proxySecrets,
+			ProxyRpcSock:  proxyRpcSock,
+			RepoPidFile:   repoPidFile,
+			ProxyPidFile:  proxyPidFile,
+			ProxyStats:    proxyStats,
 		},
 		tomlMetaData: cfg.tomlMetaData,
 		tomlComments: cfg.tomlComments,
 	}
+	if err = config.Access.Parse(); err != nil {
+		return
+	}
+	if err = config.Acme.Validate(); err != nil {
+		return
+	}
+	if err = config.Compression.Validate(); err != nil {
+		return
+	}
+	if err = config.AccessLogFormat.Validate(); err != nil {
+		return
+	}
+	if err = config.Metrics.Validate(); err != nil {
+		return
+	}
+	if err = cfg.Stats.Validate(); err != nil {
+		return
+	}
+	err = config.Server.Validate()
 	return
 }
 
@@ -433,9 +602,19 @@ func loadUsersApps(config *Config) (err error) {
 		return
 	}
 
+	err = buildAppLookups(config)
+	return
+}
+
+// buildAppLookups populates the port, domain, redirect and route lookups from
+// the config applications
+func buildAppLookups(config *Config) (err error) {
 	config.PortLookup = make(map[int]*Application)
 	config.ReservePorts = make(map[int]*Application)
 	config.DomainLookup = make(map[string]*Application)
+	config.RedirectLookup = make(map[string]*AppRedirect)
+	config.RouteLookup = make(map[string][]*AppRoute)
+	routeKeys := make(map[string]*Application)
 	for _, app := range config.Applications {
 		for _, slug := range app.Slugs {
 			for _, si := range slug.Workers {
@@ -449,11 +628,33 @@ func loadUsersApps(config *Config) (err error) {
 			}
 		}
 		for _, domain := range app.Domains {
-			if _, exists := config.DomainLookup[domain]; exists {
-				err = fmt.Errorf("domain %v duplicated by: %v", domain, app.Source)
+			var route *AppRoute
+			if route, err = NewAppRoute(domain, app); err != nil {
+				err = fmt.Errorf("%v: %v", app.Source, err)
+				return
+			}
+			if other, exists := routeKeys[route.String()]; exists {
+				err = fmt.Errorf("domain %v duplicated by: %v (already used by %v)", domain, app.Source, other.Name)
+				return
+			} else if _, exists = config.RedirectLookup[route.Host]; exists {
+				err = fmt.Errorf("domain %v duplicated by: %v (already a redirect from-host)", domain, app.Source)
 				return
 			}
-			config.DomainLookup[domain] = app
+			routeKeys[route.String()] = app
+			config.RouteLookup[route.Host] = append(config.RouteLookup[route.Host], route)
+			if _, exists := config.DomainLookup[route.Host]; !exists || route.Prefix == "" {
+				// hosts shared by path prefixes prefer the app routed at the root
+				config.DomainLookup[route.Host] = app
+			}
+		}
+		for _, redirect := range app.Redirects {
+			// redirect-only hosts are included in the domain lookup for autocert
+			if _, exists := config.DomainLookup[redirect.FromHost]; exists {
+				err = fmt.Errorf("redirect from-host %v duplicated by: %v", redirect.FromHost, app.Source)
+				return
+			}
+			config.DomainLookup[redirect.FromHost] = app
+			config.RedirectLookup[redirect.FromHost] = redirect
 		}
 		if app.AptPackage != nil {
 			if app.AptPackage.AptEnjin != "" {
@@ -464,6 +665,9 @@ func loadUsersApps(config *Config) (err error) {
 			}
 		}
 	}
+	for _, routes := range config.RouteLookup {
+		sortAppRoutes(routes)
+	}
 
 	return
 }
@@ -494,7 +698,43 @@ func (c *Config) Reload() (err error) {
 	return
 }
 
+// ReloadApplication loads the named application's app.toml and swaps it into
+// the config applications and lookups, leaving the niseroku.toml settings and
+// all other applications as they are
+func (c *Config) ReloadApplication(name string) (app *Application, err error) {
+	c.RLock()
+	existing, ok := c.Applications[name]
+	c.RUnlock()
+	if !ok {
+		err = fmt.Errorf("app not found: %v", name)
+		return
+	}
+	if app, err = NewApplication(existing.Source, c); err != nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	cfg := &Config{Applications: make(map[string]*Application, len(c.Applications))}
+	for other, otherApp := range c.Applications {
+		cfg.Applications[other] = otherApp
+	}
+	cfg.Applications[name] = app
+	if err = buildAppLookups(cfg); err != nil {
+		return
+	}
+	c.Applications = cfg.Applications
+	c.PortLookup = cfg.PortLookup
+	c.ReservePorts = cfg.ReservePorts
+	c.DomainLookup = cfg.DomainLookup
+	c.RedirectLookup = cfg.RedirectLookup
+	c.RouteLookup = cfg.RouteLookup
+	return
+}
+
 func (c *Config) MergeConfig(cfg *Config) (err error) {
+	c.Lock()
+	defer c.Unlock()
 	c.Source = cfg.Source
 	c.LogFile = cfg.LogFile
 	c.NeedRoot = cfg.NeedRoot
@@ -508,6 +748,7 @@ func (c *Config) MergeConfig(cfg *Config) (err error) {
 	c.Timeouts.SlugStartup = cfg.Timeouts.SlugStartup
 	c.Timeouts.ReadyInterval = cfg.Timeouts.ReadyInterval
 	c.Timeouts.OriginRequest = cfg.Timeouts.OriginRequest
+	c.Timeouts.UpgradeIdle = cfg.Timeouts.UpgradeIdle
 	c.ProxyLimit.TTL = cfg.ProxyLimit.TTL
 	c.ProxyLimit.Max = cfg.ProxyLimit.Max
 	c.ProxyLimit.Burst = cfg.ProxyLimit.Burst
@@ -516,6 +757,13 @@ func (c *Config) MergeConfig(cfg *Config) (err error) {
 	c.ProxyLimit.LogAllowed = cfg.ProxyLimit.LogAllowed
 	c.ProxyLimit.LogDelayed = cfg.ProxyLimit.LogDelayed
 	c.ProxyLimit.LogLimited = cfg.ProxyLimit.LogLimited
+	c.Access = cfg.Access
+	c.Acme = cfg.Acme
+	c.Compression = cfg.Compression
+	c.AccessLogFormat = cfg.AccessLogFormat
+	c.Metrics = cfg.Metrics
+	c.Stats = cfg.Stats
+	c.Server = cfg.Server
 	c.RunAs.User = cfg.RunAs.User
 	c.RunAs.Group = cfg.RunAs.Group
 	c.Ports.Git = cfg.Ports.Git
@@ -528,12 +776,15 @@ func (c *Config) MergeConfig(cfg *Config) (err error) {
 	c.Paths.Tmp = cfg.Paths.Tmp
 	c.Paths.EtcApps = cfg.Paths.EtcApps
 	c.Paths.EtcUsers = cfg.Paths.EtcUsers
+	c.Paths.EtcErrorPages = cfg.Paths.EtcErrorPages
+	c.Paths.EtcAuth = cfg.Paths.EtcAuth
 	c.Paths.TmpRun = cfg.Paths.TmpRun
 	c.Paths.TmpClone = cfg.Paths.TmpClone
 	c.Paths.TmpBuild = cfg.Paths.TmpBuild
 	c.Paths.VarLogs = cfg.Paths.VarLogs
 	c.Paths.VarRepos = cfg.Paths.VarRepos
 	c.Paths.VarCache = cfg.Paths.VarCache
+	c.Paths.VarProxyCache = cfg.Paths.VarProxyCache
 	c.Paths.VarSlugs = cfg.Paths.VarSlugs
 	c.Paths.VarSettings = cfg.Paths.VarSettings
 	c.Paths.RepoSecrets = cfg.Paths.RepoSecrets
@@ -541,10 +792,13 @@ func (c *Config) MergeConfig(cfg *Config) (err error) {
 	c.Paths.ProxyRpcSock = cfg.Paths.ProxyRpcSock
 	c.Paths.RepoPidFile = cfg.Paths.RepoPidFile
 	c.Paths.ProxyPidFile = cfg.Paths.ProxyPidFile
+	c.Paths.ProxyStats = cfg.Paths.ProxyStats
 	c.Users = cfg.Users
 	c.Applications = cfg.Applications
 	c.PortLookup = cfg.PortLookup
 	c.DomainLookup = cfg.DomainLookup
+	c.RedirectLookup = cfg.RedirectLookup
+	c.RouteLookup = cfg.RouteLookup
 	c.tomlMetaData = cfg.tomlMetaData
 	c.tomlComments = cfg.tomlComments
 	return
@@ -574,6 +828,8 @@ func (c *Config) GetTomlValue(key string) (v interface{}) {
 		v = c.Timeouts.ReadyInterval
 	case "timeouts.origin-request":
 		v = c.Timeouts.OriginRequest
+	case "timeouts.upgrade-idle":
+		v = c.Timeouts.UpgradeIdle
 	case "proxy-limit.ttl":
 		v = c.ProxyLimit.TTL
 	case "proxy-limit.max":
@@ -590,6 +846,48 @@ func (c *Config) GetTomlValue(key string) (v interface{}) {
 		v = c.ProxyLimit.LogDelayed
 	case "proxy-limit.log-limited":
 		v = c.ProxyLimit.LogLimited
+	case "access.denied-status":
+		v = c.Access.DeniedStatus
+	case "access.denied-message":
+		v = c.Access.DeniedMessage
+	case "acme.directory-url":
+		v = c.Acme.DirectoryUrl
+	case "acme.ca-roots":
+		v = c.Acme.CaRoots
+	case "acme.eab-kid":
+		v = c.Acme.EabKid
+	case "acme.eab-key":
+		v = c.Acme.EabKey
+	case "compression.enable":
+		v = c.Compression.IsEnabled()
+	case "compression.min-size":
+		v = c.Compression.GetMinSize()
+	case "access-log.format":
+		v = c.AccessLogFormat.Format
+	case "access-log.template":
+		v = c.AccessLogFormat.Template
+	case "metrics.listen":
+		v = c.Metrics.Listen
+	case "stats.seconds":
+		v = c.Stats.Seconds
+	case "stats.minutes":
+		v = c.Stats.Minutes
+	case "stats.max-addresses":
+		v = c.Stats.MaxAddresses
+	case "server.read-header-timeout":
+		v = c.Server.ReadHeaderTimeout
+	case "server.read-timeout":
+		v = c.Server.ReadTimeout
+	case "server.write-timeout":
+		v = c.Server.WriteTimeout
+	case "server.idle-timeout":
+		v = c.Server.IdleTimeout
+	case "server.max-header-bytes":
+		v = c.Server.MaxHeaderBytes
+	case "server.max-body-size":
+		v = c.Server.MaxBodySize
+	case "server.proxy-protocol":
+		v = c.Server.ProxyProtocol
 	case "run-as.user":
 		v = c.RunAs.User
 	case "run-as.group":
@@ -638,6 +936,8 @@ func (c *Config) SetTomlValue(key string, v string) (err error) {
 		c.Timeouts.ReadyInterval, err = c.parseTimeDurationValue(v)
 	case "timeouts.origin-request":
 		c.Timeouts.OriginRequest, err = c.parseTimeDurationValue(v)
+	case "timeouts.upgrade-idle":
+		c.Timeouts.UpgradeIdle, err = c.parseTimeDurationValue(v)
 	case "proxy-limit.ttl":
 		c.ProxyLimit.TTL, err = c.parseTimeDurationValue(v)
 	case "proxy-limit.max":
@@ -654,6 +954,65 @@ func (c *Config) SetTomlValue(key string, v string) (err error) {
 		c.ProxyLimit.LogDelayed, err = c.parseBoolValue(v)
 	case "proxy-limit.log-limited":
 		c.ProxyLimit.LogLimited, err = c.parseBoolValue(v)
+	case "access.denied-status":
+		c.Access.DeniedStatus, err = c.parseIntValue(v)
+	case "access.denied-message":
+		c.Access.DeniedMessage, err = c.parseStringValue(v)
+	case "acme.directory-url":
+		c.Acme.DirectoryUrl, err = c.parseStringValue(v)
+	case "acme.ca-roots":
+		c.Acme.CaRoots, err = c.parseStringValue(v)
+	case "acme.eab-kid":
+		c.Acme.EabKid, err = c.parseStringValue(v)
+	case "acme.eab-key":
+		c.Acme.EabKey, err = c.parseStringValue(v)
+	case "compression.enable":
+		var enable bool
+		if enable, err = c.parseBoolValue(v); err == nil {
+			c.Compression.Enable = &enable
+		}
+	case "access-log.format":
+		c.AccessLogFormat.Format, err = c.parseStringValue(v)
+	case "access-log.template":
+		c.AccessLogFormat.Template, err = c.parseStringValue(v)
+	case "metrics.listen":
+		c.Metrics.Listen, err = c.parseStringValue(v)
+	case "stats.seconds":
+		c.Stats.Seconds, err = c.parseIntValue(v)
+	case "stats.minutes":
+		c.Stats.Minutes, err = c.parseIntValue(v)
+	case "stats.max-addresses":
+		c.Stats.MaxAddresses, err = c.parseIntValue(v)
+	case "server.read-header-timeout":
+		c.Server.ReadHeaderTimeout, err = c.parseTimeDurationValue(v)
+	case "server.read-timeout":
+		c.Server.ReadTimeout, err = c.parseTimeDurationValue(v)
+	case "server.write-timeout":
+		c.Server.WriteTimeout, err = c.parseTimeDurationValue(v)
+	case "server.idle-timeout":
+		c.Server.IdleTimeout, err = c.parseTimeDurationValue(v)
+	case "server.max-header-bytes":
+		c.Server.MaxHeaderBytes, err = c.parseIntValue(v)
+	case "server.proxy-protocol":
+		var proxyProtocol bool
+		if proxyProtocol, err = c.parseBoolValue(v); err == nil {
+			if proxyProtocol && len(c.Server.TrustedProxies) == 0 {
+				err = fmt.Errorf("server.proxy-protocol requires server.trusted-proxies")
+			} else {
+				c.Server.ProxyProtocol = proxyProtocol
+			}
+		}
+	case "server.max-body-size":
+		var maxBodySize int
+		if maxBodySize, err = c.parseIntValue(v); err == nil {
+			c.Server.MaxBodySize = int64(maxBodySize)
+		}
+	case "compression.min-size":
+		var minSize int
+		if minSize, err = c.parseIntValue(v); err == nil {
+			size := int64(minSize)
+			c.Compression.MinSize = &size
+		}
 	case "run-as.user":
 		c.RunAs.User, err = c.parseStringValue(v)
 	case "run-as.group":
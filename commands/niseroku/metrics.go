@@ -0,0 +1,216 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-corelibs/maps"
+)
+
+var (
+	MetricsLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	MetricsDelayBuckets   = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10}
+
+	metricsHelp = map[string]string{
+		"niseroku_http_requests_total":            "Total proxied requests by app, host and status code.",
+		"niseroku_http_request_duration_seconds":  "Proxied request latency by app.",
+		"niseroku_http_requests_in_flight":        "Requests currently being proxied by app.",
+		"niseroku_access_denied_total":            "Requests denied by access lists by app.",
+		"niseroku_auth_denied_total":              "Requests without valid basic-auth credentials by app.",
+		"niseroku_request_too_large_total":        "Requests rejected for exceeding max-body-size by app.",
+		"niseroku_rate_limit_delayed_total":       "Requests delayed by rate limiting by app.",
+		"niseroku_rate_limit_delay_seconds":       "Time requests spent delayed by rate limiting by app.",
+		"niseroku_rate_limit_rejected_total":      "Requests rejected by rate limiting by app.",
+		"niseroku_slug_restarts_total":            "Slug worker restarts by app.",
+		"niseroku_deploys_total":                  "Application deployments by app and outcome.",
+		"niseroku_proxy_reloads_total":            "Reverse-proxy configuration reloads.",
+		"niseroku_health_check_transitions_total": "Worker health state changes by app and state.",
+		"niseroku_origin_retries_total":           "Origin requests retried by app.",
+		"niseroku_circuit_breaker_open_total":     "Worker circuit breakers opened by app.",
+		"niseroku_canary_decisions_total":         "Canary promotions and rollbacks by app and decision.",
+		"niseroku_mirror_requests_total":          "Requests mirrored to a shadow slug by app and result.",
+	}
+)
+
+type metricHistogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Metrics is a minimal store of counters, gauges and histograms which renders
+// as the Prometheus text exposition format
+type Metrics struct {
+	counters   map[string]map[string]float64
+	gauges     map[string]map[string]float64
+	histograms map[string]map[string]*metricHistogram
+
+	sync.RWMutex
+}
+
+func NewMetrics() (m *Metrics) {
+	m = new(Metrics)
+	m.counters = make(map[string]map[string]float64)
+	m.gauges = make(map[string]map[string]float64)
+	m.histograms = make(map[string]map[string]*metricHistogram)
+	return
+}
+
+// Inc increments the named counter, labels are given as name, value pairs
+func (m *Metrics) Inc(name string, labels ...string) {
+	m.Add(name, 1, labels...)
+}
+
+// Add adds the value to the named counter, labels are given as name, value
+// pairs
+func (m *Metrics) Add(name string, value float64, labels ...string) {
+	key := formatMetricLabels(labels...)
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.counters[name]; !ok {
+		m.counters[name] = make(map[string]float64)
+	}
+	m.counters[name][key] += value
+}
+
+// Set sets the value of the named gauge, labels are given as name, value pairs
+func (m *Metrics) Set(name string, value float64, labels ...string) {
+	key := formatMetricLabels(labels...)
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.gauges[name]; !ok {
+		m.gauges[name] = make(map[string]float64)
+	}
+	m.gauges[name][key] = value
+}
+
+// ResetGauge removes all values of the named gauge
+func (m *Metrics) ResetGauge(name string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.gauges, name)
+}
+
+// Observe records the value in the named histogram, labels are given as name,
+// value pairs
+func (m *Metrics) Observe(name string, buckets []float64, value float64, labels ...string) {
+	key := formatMetricLabels(labels...)
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.histograms[name]; !ok {
+		m.histograms[name] = make(map[string]*metricHistogram)
+	}
+	h, ok := m.histograms[name][key]
+	if !ok {
+		h = &metricHistogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		m.histograms[name][key] = h
+	}
+	for idx, bound := range h.buckets {
+		if value <= bound {
+			h.counts[idx] += 1
+		}
+	}
+	h.count += 1
+	h.sum += value
+}
+
+// String returns all metrics in the Prometheus text exposition format
+func (m *Metrics) String() (text string) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var buf strings.Builder
+	writeHeader := func(name, kind string) {
+		if help, ok := metricsHelp[name]; ok {
+			buf.WriteString("# HELP " + name + " " + help + "\n")
+		}
+		buf.WriteString("# TYPE " + name + " " + kind + "\n")
+	}
+
+	for _, name := range maps.SortedKeys(m.counters) {
+		writeHeader(name, "counter")
+		for _, key := range maps.SortedKeys(m.counters[name]) {
+			buf.WriteString(name + key + " " + formatMetricValue(m.counters[name][key]) + "\n")
+		}
+	}
+
+	for _, name := range maps.SortedKeys(m.gauges) {
+		writeHeader(name, "gauge")
+		for _, key := range maps.SortedKeys(m.gauges[name]) {
+			buf.WriteString(name + key + " " + formatMetricValue(m.gauges[name][key]) + "\n")
+		}
+	}
+
+	for _, name := range maps.SortedKeys(m.histograms) {
+		writeHeader(name, "histogram")
+		for _, key := range maps.SortedKeys(m.histograms[name]) {
+			h := m.histograms[name][key]
+			for idx, bound := range h.buckets {
+				le := formatMetricLabel("le", formatMetricValue(bound))
+				buf.WriteString(name + "_bucket" + appendMetricLabel(key, le) + " " + strconv.FormatUint(h.counts[idx], 10) + "\n")
+			}
+			buf.WriteString(name + "_bucket" + appendMetricLabel(key, `le="+Inf"`) + " " + strconv.FormatUint(h.count, 10) + "\n")
+			buf.WriteString(name + "_sum" + key + " " + formatMetricValue(h.sum) + "\n")
+			buf.WriteString(name + "_count" + key + " " + strconv.FormatUint(h.count, 10) + "\n")
+		}
+	}
+
+	text = buf.String()
+	return
+}
+
+// formatMetricLabels returns the sorted {name="value",...} label set for the
+// given name, value pairs, or an empty string if there are no labels
+func formatMetricLabels(labels ...string) (key string) {
+	if len(labels) < 2 {
+		return
+	}
+	var pairs []string
+	for idx := 0; idx+1 < len(labels); idx += 2 {
+		pairs = append(pairs, formatMetricLabel(labels[idx], labels[idx+1]))
+	}
+	sort.Strings(pairs)
+	key = "{" + strings.Join(pairs, ",") + "}"
+	return
+}
+
+func formatMetricLabel(name, value string) (label string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	label = fmt.Sprintf(`%s="%s"`, name, value)
+	return
+}
+
+// appendMetricLabel adds the label to the label set key
+func appendMetricLabel(key, label string) (modified string) {
+	if key == "" {
+		modified = "{" + label + "}"
+	} else {
+		modified = strings.TrimSuffix(key, "}") + "," + label + "}"
+	}
+	return
+}
+
+func formatMetricValue(value float64) (text string) {
+	text = strconv.FormatFloat(value, 'g', -1, 64)
+	return
+}
@@ -104,6 +104,8 @@ handled directly.
 				makeCommandReload(c, app),
 				makeCommandStop(c, app),
 				makeCommandStatus(c, app),
+				makeCommandStats(c, app),
+				makeCommandCerts(c, app),
 				makeCommandConfig(c, app),
 				makeCommandDeploySlug(c, app),
 				makeCommandFixFs(c, app),
@@ -120,6 +122,8 @@ handled directly.
 						makeCommandAppStop(c, app),
 						makeCommandAppRestart(c, app),
 						makeCommandAppRename(c, app),
+						makeCommandAppCachePurge(c, app),
+						makeCommandAppCanary(c, app),
 					},
 				},
 			},
@@ -0,0 +1,125 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type accessRecordKey struct{}
+
+// AccessRecord collects the per-request details which are only known while
+// the request is being proxied
+type AccessRecord struct {
+	Port   int
+	Worker string
+	Delay  time.Duration
+	Cache  string
+	Bytes  int64
+
+	sync.RWMutex
+}
+
+// newAccessRecord returns the ResponseWriter and Request to use for the rest
+// of the request handling, with a new AccessRecord attached
+func newAccessRecord(w http.ResponseWriter, r *http.Request) (rw http.ResponseWriter, req *http.Request) {
+	record := new(AccessRecord)
+	rw = &accessLogWriter{ResponseWriter: w, record: record}
+	req = r.WithContext(context.WithValue(r.Context(), accessRecordKey{}, record))
+	return
+}
+
+// getAccessRecord returns the AccessRecord of the request, or nil if there is
+// none; all AccessRecord methods are safe to call on a nil record
+func getAccessRecord(r *http.Request) (record *AccessRecord) {
+	record, _ = r.Context().Value(accessRecordKey{}).(*AccessRecord)
+	return
+}
+
+func (a *AccessRecord) SetUpstream(slug *Slug, port int) {
+	if a == nil {
+		return
+	}
+	var worker string
+	for _, sw := range slug.GetLiveWorkers() {
+		if sw.Port == port {
+			worker = sw.Hash
+			break
+		}
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.Port = port
+	a.Worker = worker
+}
+
+func (a *AccessRecord) SetDelay(delay time.Duration) {
+	if a == nil {
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.Delay = delay
+}
+
+func (a *AccessRecord) SetCache(cache string) {
+	if a == nil {
+		return
+	}
+	a.Lock()
+	defer a.Unlock()
+	a.Cache = cache
+}
+
+func (a *AccessRecord) addBytes(n int) {
+	a.Lock()
+	defer a.Unlock()
+	a.Bytes += int64(n)
+}
+
+// Apply copies the recorded details to the given access log entry
+func (a *AccessRecord) Apply(entry *AccessLogEntry) {
+	if a == nil {
+		return
+	}
+	a.RLock()
+	defer a.RUnlock()
+	entry.Port = a.Port
+	entry.Worker = a.Worker
+	entry.Delay = a.Delay
+	entry.Cache = a.Cache
+	entry.Bytes = a.Bytes
+}
+
+// accessLogWriter counts the response body bytes written to the client
+type accessLogWriter struct {
+	http.ResponseWriter
+
+	record *AccessRecord
+}
+
+func (w *accessLogWriter) Write(p []byte) (n int, err error) {
+	n, err = w.ResponseWriter.Write(p)
+	w.record.addBytes(n)
+	return
+}
+
+func (w *accessLogWriter) Unwrap() (rw http.ResponseWriter) {
+	rw = w.ResponseWriter
+	return
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kataras/requestid"
+)
+
+// checkAccess returns true if the remote address is denied by either the
+// niseroku.toml or the app.toml access lists, along with the response status
+// and message to use
+func (rp *ReverseProxy) checkAccess(app *Application, remoteAddr string) (denied bool, status int, message string) {
+	rp.config.RLock()
+	global := rp.config.Access
+	rp.config.RUnlock()
+
+	if denied = global.Denies(remoteAddr); !denied && app != nil {
+		denied = app.Access.Denies(remoteAddr)
+	}
+	if !denied {
+		return
+	}
+
+	status = global.GetDeniedStatus()
+	message = global.GetDeniedMessage()
+	if app != nil && app.Access != nil {
+		if app.Access.DeniedStatus > 0 {
+			status = app.Access.DeniedStatus
+			message = app.Access.GetDeniedMessage()
+		}
+		if app.Access.DeniedMessage != "" {
+			message = app.Access.DeniedMessage
+		}
+	}
+	return
+}
+
+// denyAccess writes the access denied response if the remote address is not
+// allowed, returning true if the request was denied
+func (rp *ReverseProxy) denyAccess(w http.ResponseWriter, r *http.Request, domain string, app *Application, remoteAddr string) (denied bool) {
+	var status int
+	var message string
+	if denied, status, message = rp.checkAccess(app, remoteAddr); !denied {
+		return
+	}
+
+	trackingKeys := []string{"__denied__", "denied|addr|" + remoteAddr}
+	if app != nil {
+		trackingKeys = append(trackingKeys, "denied|app|"+app.Name, "denied|host|"+domain)
+	}
+	rp.tracking.Increment(trackingKeys...)
+	go func() {
+		// denied requests are not in-flight, keep them visible for a while
+		time.Sleep(DefaultDeniedStatLifetime)
+		rp.tracking.Decrement(trackingKeys...)
+	}()
+
+	reqUrl, _, _, _ := DecomposeUrl(r)
+	rp.LogInfoF("[access] denied - %v - %v - %v", requestid.Get(r), remoteAddr, reqUrl)
+	start := time.Now()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(message))
+	if app != nil {
+		app.LogAccessF(status, remoteAddr, r, start)
+		rp.metrics.Inc("niseroku_access_denied_total", "app", app.Name)
+	}
+	return
+}
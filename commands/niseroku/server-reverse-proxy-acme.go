@@ -0,0 +1,121 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func (rp *ReverseProxy) newAutocertManager() (m *autocert.Manager, err error) {
+	m = &autocert.Manager{
+		Cache:      autocert.DirCache(rp.config.Paths.ProxySecrets),
+		Prompt:     autocert.AcceptTOS,
+		Email:      rp.config.AccountEmail,
+		HostPolicy: rp.autocertHostPolicy,
+	}
+	if m.Client, err = rp.config.Acme.Client(); err != nil {
+		return
+	}
+	m.ExternalAccountBinding, err = rp.config.Acme.ExternalAccountBinding()
+	return
+}
+
+func (rp *ReverseProxy) getAutocert() (m *autocert.Manager) {
+	rp.RLock()
+	defer rp.RUnlock()
+	m = rp.autocert
+	return
+}
+
+// autocertHTTPHandler handles acme http-01 challenges with the current
+// autocert manager, passing all other requests to the fallback
+func (rp *ReverseProxy) autocertHTTPHandler(fallback http.Handler) (h http.Handler) {
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rp.getAutocert().HTTPHandler(fallback).ServeHTTP(w, r)
+	})
+	return
+}
+
+// renewCertificate removes the cached autocert certificates for the domain
+// and starts obtaining a new one, restoring the cached certificates if the
+// renewal fails
+func (rp *ReverseProxy) renewCertificate(domain string) (err error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if !rp.config.EnableSSL {
+		err = fmt.Errorf("enable-ssl is not set")
+		return
+	} else if err = rp.autocertHostPolicy(context.Background(), domain); err != nil {
+		return
+	} else if rp.certs.Lookup(domain) != nil {
+		err = fmt.Errorf("%v is using a static certificate", domain)
+		return
+	}
+
+	var renewed *autocert.Manager
+	if renewed, err = rp.newAutocertManager(); err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	cache := renewed.Cache
+	previous := make(map[string][]byte)
+	for _, name := range []string{domain, domain + "+rsa"} {
+		if data, ee := cache.Get(ctx, name); ee == nil {
+			previous[name] = data
+		} else if !errors.Is(ee, autocert.ErrCacheMiss) {
+			err = fmt.Errorf("error reading cached certificate: %v - %v", name, ee)
+			return
+		}
+	}
+	for name := range previous {
+		if err = cache.Delete(ctx, name); err != nil {
+			err = fmt.Errorf("error removing cached certificate: %v - %v", name, err)
+			return
+		}
+	}
+
+	// a new manager does not have the previous certificates in memory
+	rp.Lock()
+	rp.autocert = renewed
+	rp.Unlock()
+
+	go func() {
+		rp.LogInfoF("[certs] renewing certificate: %v", domain)
+		hello := &tls.ClientHelloInfo{
+			ServerName:       domain,
+			SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+			SupportedCurves:  []tls.CurveID{tls.CurveP256},
+			CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		}
+		if _, ee := renewed.GetCertificate(hello); ee != nil {
+			rp.LogErrorF("[certs] error renewing certificate: %v - %v", domain, ee)
+			for name, data := range previous {
+				if eee := cache.Put(ctx, name, data); eee != nil {
+					rp.LogErrorF("[certs] error restoring cached certificate: %v - %v", name, eee)
+				}
+			}
+			return
+		}
+		rp.LogInfoF("[certs] renewed certificate: %v", domain)
+	}()
+	return
+}
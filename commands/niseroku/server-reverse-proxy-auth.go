@@ -0,0 +1,185 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kataras/requestid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authDummyHash is compared against when the user is not found so that
+// unknown users take as long to reject as known users, it is a DefaultCost
+// bcrypt hash so that the comparison takes as long as for real hashes
+var authDummyHash = []byte("$2a$10$gsbrzsYGNsGMg6RdidO2veoOPODP6naCBBgGm1bnpJ24HjjoxdT5e")
+
+// AppCredentials are the bcrypt hashes of one htpasswd file, successful
+// checks are remembered to avoid repeating the bcrypt comparison for every
+// request
+type AppCredentials struct {
+	hashes   map[string][]byte
+	verified map[string][sha256.Size]byte
+
+	sync.RWMutex
+}
+
+// ParseHtpasswd reads the user:hash lines of the htpasswd file given, blank
+// lines and lines starting with a hash are ignored and only bcrypt hashes are
+// supported
+func ParseHtpasswd(path string) (creds *AppCredentials, err error) {
+	var fh *os.File
+	if fh, err = os.Open(path); err != nil {
+		return
+	}
+	defer func() { _ = fh.Close() }()
+
+	creds = &AppCredentials{
+		hashes:   make(map[string][]byte),
+		verified: make(map[string][sha256.Size]byte),
+	}
+	var lineNo int
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		lineNo += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		user, hash, found := strings.Cut(line, ":")
+		if !found || user == "" {
+			err = fmt.Errorf("%v:%d: expected user:hash", path, lineNo)
+			return
+		} else if _, ee := bcrypt.Cost([]byte(hash)); ee != nil {
+			err = fmt.Errorf("%v:%d: %v is not a bcrypt hash", path, lineNo, user)
+			return
+		}
+		creds.hashes[user] = []byte(hash)
+	}
+	err = scanner.Err()
+	return
+}
+
+// Check returns true if the user exists and the password matches
+func (c *AppCredentials) Check(user, password string) (ok bool) {
+	sum := sha256.Sum256([]byte(user + ":" + password))
+
+	c.RLock()
+	hash, found := c.hashes[user]
+	known, verified := c.verified[user]
+	c.RUnlock()
+
+	if !found {
+		_ = bcrypt.CompareHashAndPassword(authDummyHash, []byte(password))
+		return
+	} else if verified && subtle.ConstantTimeCompare(known[:], sum[:]) == 1 {
+		ok = true
+		return
+	} else if ok = bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil; ok {
+		c.Lock()
+		c.verified[user] = sum
+		c.Unlock()
+	}
+	return
+}
+
+type AuthFiles struct {
+	data map[string]*AppCredentials
+
+	sync.RWMutex
+}
+
+func NewAuthFiles() (af *AuthFiles) {
+	af = new(AuthFiles)
+	af.data = make(map[string]*AppCredentials)
+	return
+}
+
+func (af *AuthFiles) Get(app string) (creds *AppCredentials) {
+	af.RLock()
+	defer af.RUnlock()
+	creds = af.data[app]
+	return
+}
+
+func (af *AuthFiles) Replace(data map[string]*AppCredentials) {
+	af.Lock()
+	defer af.Unlock()
+	af.data = data
+}
+
+// reloadAuthFiles parses the htpasswd files of all apps with basic-auth
+// enabled, apps with files that fail to parse have no valid credentials and
+// all requests are denied until the file is fixed
+func (rp *ReverseProxy) reloadAuthFiles() {
+	rp.config.RLock()
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+
+	var count int
+	data := make(map[string]*AppCredentials)
+	for _, app := range apps {
+		if !app.Auth.Enabled() {
+			continue
+		}
+		if creds, err := ParseHtpasswd(app.Auth.GetFile(app)); err != nil {
+			rp.LogErrorF("[auth] error loading %v credentials: %v", app.Name, err)
+		} else {
+			data[app.Name] = creds
+			count += len(creds.hashes)
+		}
+	}
+
+	rp.auth.Replace(data)
+	rp.LogInfoF("[auth] loaded %d credentials", count)
+}
+
+// denyAuth writes the 401 response if the app requires basic-auth and the
+// request does not have valid credentials, returning true if the request was
+// denied
+func (rp *ReverseProxy) denyAuth(w http.ResponseWriter, r *http.Request, app *Application, remoteAddr string) (denied bool) {
+	if app == nil || !app.Auth.Enabled() || app.Auth.IsExempt(r.URL) {
+		return
+	}
+
+	if user, password, ok := r.BasicAuth(); ok {
+		if creds := rp.auth.Get(app.Name); creds != nil && creds.Check(user, password) {
+			// the origin does not need the proxy credentials
+			r.Header.Del("Authorization")
+			return
+		}
+		reqUrl, _, _, _ := DecomposeUrl(r)
+		rp.LogInfoF("[auth] denied - %v - %v - %v - %q", requestid.Get(r), remoteAddr, reqUrl, user)
+	}
+
+	denied = true
+	start := time.Now()
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, app.Auth.GetRealm()))
+	rp.serveError(w, r, app, http.StatusUnauthorized)
+	app.LogAccessF(http.StatusUnauthorized, remoteAddr, r, start)
+	rp.metrics.Inc("niseroku_auth_denied_total", "app", app.Name)
+	return
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kataras/requestid"
+)
+
+func (rp *ReverseProxy) getMaxBodySize(app *Application) (limit int64) {
+	rp.config.RLock()
+	global := rp.config.Server.MaxBodySize
+	rp.config.RUnlock()
+	if app != nil {
+		limit = app.Proxy.GetMaxBodySize(global)
+	} else {
+		limit = global
+	}
+	return
+}
+
+// limitRequestBody rejects requests with a Content-Length larger than the
+// app's max-body-size, returning true if the request was rejected; bodies of
+// unknown length are limited while being read by the origin request
+func (rp *ReverseProxy) limitRequestBody(w http.ResponseWriter, r *http.Request, domain string, app *Application, remoteAddr string) (rejected bool) {
+	limit := rp.getMaxBodySize(app)
+	if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+	if rejected = r.ContentLength > limit; rejected {
+		start := time.Now()
+		rp.serveTooLarge(w, r, domain, app, remoteAddr)
+		if app != nil {
+			app.LogAccessF(http.StatusRequestEntityTooLarge, remoteAddr, r, start)
+			rp.recordRequest(app, domain, remoteAddr, http.StatusRequestEntityTooLarge, start)
+		}
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return
+}
+
+// serveTooLarge writes the 413 response and tracks the rejected request
+func (rp *ReverseProxy) serveTooLarge(w http.ResponseWriter, r *http.Request, domain string, app *Application, remoteAddr string) {
+	trackingKeys := []string{"__toolarge__", "toolarge|addr|" + remoteAddr}
+	if app != nil {
+		trackingKeys = append(trackingKeys, "toolarge|app|"+app.Name, "toolarge|host|"+domain)
+		rp.metrics.Inc("niseroku_request_too_large_total", "app", app.Name)
+	}
+	rp.tracking.Increment(trackingKeys...)
+	go func() {
+		// rejected requests are not in-flight, keep them visible for a while
+		time.Sleep(DefaultDeniedStatLifetime)
+		rp.tracking.Decrement(trackingKeys...)
+	}()
+
+	reqUrl, _, _, _ := DecomposeUrl(r)
+	rp.LogInfoF("[body] too large - %v - %v - %v", requestid.Get(r), remoteAddr, reqUrl)
+	rp.serveError(w, r, app, http.StatusRequestEntityTooLarge)
+}
+
+// isTooLargeError returns true if the error is from reading a request body
+// limited by limitRequestBody
+func isTooLargeError(err error) (tooLarge bool) {
+	var maxBytesErr *http.MaxBytesError
+	tooLarge = errors.As(err, &maxBytesErr)
+	return
+}
+
+// clientBodyReader records the first error reading the client request body,
+// other than io.EOF, so that origin request failures caused by the client are
+// not counted against the worker
+type clientBodyReader struct {
+	io.ReadCloser
+
+	err error
+	sync.Mutex
+}
+
+func (b *clientBodyReader) Read(p []byte) (n int, err error) {
+	if n, err = b.ReadCloser.Read(p); err != nil && err != io.EOF {
+		b.Lock()
+		if b.err == nil {
+			b.err = err
+		}
+		b.Unlock()
+	}
+	return
+}
+
+// Err returns the first error reading the client request body
+func (b *clientBodyReader) Err() (err error) {
+	b.Lock()
+	defer b.Unlock()
+	err = b.err
+	return
+}
+
+// isClientBodyError returns true if reading the body of the origin request
+// failed, ie: the client went away mid-upload or sent too large a body
+func isClientBodyError(req *http.Request) (failed bool) {
+	if body, ok := req.Body.(*clientBodyReader); ok {
+		failed = body.Err() != nil
+	}
+	return
+}
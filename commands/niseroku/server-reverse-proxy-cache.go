@@ -0,0 +1,706 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// cacheableStatus are the response status codes which may be cached
+	cacheableStatus = map[int]struct{}{
+		http.StatusOK:                   {},
+		http.StatusNonAuthoritativeInfo: {},
+		http.StatusMultipleChoices:      {},
+		http.StatusMovedPermanently:     {},
+		http.StatusPermanentRedirect:    {},
+		http.StatusNotFound:             {},
+		http.StatusGone:                 {},
+	}
+	// cacheSkipHeaders are response headers which are set per-request by the
+	// reverse-proxy and are not stored with cached responses
+	cacheSkipHeaders = map[string]struct{}{
+		"X-Request-Id":              {},
+		"Strict-Transport-Security": {},
+		"Age":                       {},
+		"Content-Length":            {},
+		"Connection":                {},
+		"Keep-Alive":                {},
+		"Transfer-Encoding":         {},
+	}
+	// cacheRefreshHeaders are the stored response headers updated when the
+	// origin revalidates a cached response
+	cacheRefreshHeaders = []string{"Cache-Control", "Date", "Etag", "Expires", "Last-Modified"}
+)
+
+// CachedResponse is a stored origin response, the body is either held in
+// memory or written to a file once the memory limit is reached
+type CachedResponse struct {
+	Key     string
+	Path    string
+	Vary    map[string]string
+	Status  int
+	Header  http.Header
+	Size    int64
+	Stored  time.Time
+	Expires time.Time
+
+	body    []byte
+	file    string
+	element *list.Element
+}
+
+func (c *CachedResponse) HasValidators() (ok bool) {
+	ok = c.Header.Get("Etag") != "" || c.Header.Get("Last-Modified") != ""
+	return
+}
+
+// CacheHit is a snapshot of a CachedResponse, Close must be called to release
+// the body of responses which overflowed to disk
+type CacheHit struct {
+	Status int
+	Header http.Header
+	Size   int64
+	Stored time.Time
+	Fresh  bool
+
+	body  io.ReadCloser
+	entry *CachedResponse
+}
+
+func (h *CacheHit) Close() {
+	if h != nil && h.body != nil {
+		_ = h.body.Close()
+		h.body = nil
+	}
+}
+
+// AppResponseCache is the least-recently-used cache of one application's
+// responses
+type AppResponseCache struct {
+	name string
+	dir  string
+
+	maxSize      int64
+	memorySize   int64
+	maxEntrySize int64
+
+	entries map[string][]*CachedResponse
+	lru     *list.List
+	size    int64
+	memory  int64
+	seq     uint64
+
+	sync.Mutex
+}
+
+func newAppResponseCache(name, dir string) (ac *AppResponseCache) {
+	ac = &AppResponseCache{
+		name:    name,
+		dir:     dir,
+		entries: make(map[string][]*CachedResponse),
+		lru:     list.New(),
+	}
+	return
+}
+
+func (ac *AppResponseCache) configure(settings *AppCache) {
+	ac.Lock()
+	defer ac.Unlock()
+	ac.maxSize = settings.GetMaxSize()
+	ac.memorySize = settings.GetMemorySize()
+	ac.maxEntrySize = settings.GetMaxEntrySize()
+	ac.balance()
+}
+
+// Lookup returns the cached variant for the request, or nil if there is no
+// usable entry; stale entries are only returned if they can be revalidated
+func (ac *AppResponseCache) Lookup(r *http.Request) (hit *CacheHit) {
+	ac.Lock()
+	defer ac.Unlock()
+	for _, entry := range ac.entries[getCacheKey(r)] {
+		if !matchCacheVary(entry.Vary, r) {
+			continue
+		}
+		fresh := time.Now().Before(entry.Expires)
+		if !fresh && !entry.HasValidators() {
+			return
+		}
+		hit = &CacheHit{
+			Status: entry.Status,
+			Header: entry.Header.Clone(),
+			Size:   entry.Size,
+			Stored: entry.Stored,
+			Fresh:  fresh,
+			entry:  entry,
+		}
+		if entry.file != "" {
+			// an open file remains readable if the entry is evicted meanwhile
+			if fh, err := os.Open(entry.file); err != nil {
+				ac.remove(entry)
+				hit = nil
+				return
+			} else {
+				hit.body = fh
+			}
+		} else {
+			hit.body = io.NopCloser(bytes.NewReader(entry.body))
+		}
+		ac.lru.MoveToFront(entry.element)
+		return
+	}
+	return
+}
+
+// Store adds the response to the cache, replacing any existing entry for the
+// same request variant
+func (ac *AppResponseCache) Store(r *http.Request, status int, header http.Header, body []byte, expires time.Time) {
+	ac.Lock()
+	defer ac.Unlock()
+	size := int64(len(body))
+	if size > ac.maxEntrySize {
+		return
+	}
+
+	key := getCacheKey(r)
+	vary := getCacheVary(header, r)
+	for _, existing := range ac.entries[key] {
+		if matchCacheVary(existing.Vary, r) {
+			ac.remove(existing)
+			break
+		}
+	}
+
+	stored := make(http.Header)
+	for k, v := range header {
+		if _, skip := cacheSkipHeaders[k]; !skip {
+			stored[k] = append([]string(nil), v...)
+		}
+	}
+
+	entry := &CachedResponse{
+		Key:     key,
+		Path:    r.URL.Path,
+		Vary:    vary,
+		Status:  status,
+		Header:  stored,
+		Size:    size,
+		Stored:  time.Now(),
+		Expires: expires,
+		body:    body,
+	}
+	entry.element = ac.lru.PushFront(entry)
+	ac.entries[key] = append(ac.entries[key], entry)
+	ac.size += size
+	ac.memory += size
+	ac.balance()
+}
+
+// Refresh updates the cached entry with the headers of an origin 304 response,
+// returning false if the entry is no longer cacheable
+func (ac *AppResponseCache) Refresh(hit *CacheHit, header http.Header) (ok bool) {
+	ac.Lock()
+	defer ac.Unlock()
+	entry := hit.entry
+	if entry.element == nil {
+		// evicted or purged while revalidating
+		return
+	}
+	for _, key := range cacheRefreshHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			entry.Header[key] = append([]string(nil), values...)
+		}
+	}
+	var expires time.Time
+	if expires, ok = getCacheExpiry(entry.Status, entry.Header); !ok {
+		ac.remove(entry)
+		return
+	}
+	entry.Stored = time.Now()
+	entry.Expires = expires
+	hit.Header = entry.Header.Clone()
+	hit.Stored = entry.Stored
+	hit.Fresh = true
+	return
+}
+
+// Purge removes all entries with a request path starting with the given
+// prefix, an empty prefix removes all entries
+func (ac *AppResponseCache) Purge(prefix string) (count int) {
+	ac.Lock()
+	defer ac.Unlock()
+	for _, variants := range ac.entries {
+		// remove modifies the variants slice
+		for _, entry := range append([]*CachedResponse(nil), variants...) {
+			if strings.HasPrefix(entry.Path, prefix) {
+				ac.remove(entry)
+				count += 1
+			}
+		}
+	}
+	return
+}
+
+// Clear removes all entries and the overflow directory
+func (ac *AppResponseCache) Clear() {
+	ac.Lock()
+	defer ac.Unlock()
+	for element := ac.lru.Front(); element != nil; element = element.Next() {
+		element.Value.(*CachedResponse).element = nil
+	}
+	ac.entries = make(map[string][]*CachedResponse)
+	ac.lru.Init()
+	ac.size = 0
+	ac.memory = 0
+	_ = os.RemoveAll(ac.dir)
+}
+
+// balance moves the least recently used bodies to disk until the memory limit
+// is met and evicts the least recently used entries until the size limit is met
+func (ac *AppResponseCache) balance() {
+	for element := ac.lru.Back(); element != nil && ac.memory > ac.memorySize; {
+		entry := element.Value.(*CachedResponse)
+		element = element.Prev()
+		if entry.file == "" {
+			if err := ac.overflow(entry); err != nil {
+				ac.remove(entry)
+			}
+		}
+	}
+	for ac.size > ac.maxSize {
+		if element := ac.lru.Back(); element != nil {
+			ac.remove(element.Value.(*CachedResponse))
+		} else {
+			break
+		}
+	}
+}
+
+func (ac *AppResponseCache) overflow(entry *CachedResponse) (err error) {
+	if err = os.MkdirAll(ac.dir, 0770); err != nil {
+		return
+	}
+	ac.seq += 1
+	file := filepath.Join(ac.dir, strconv.FormatUint(ac.seq, 36)+".body")
+	if err = os.WriteFile(file, entry.body, 0660); err != nil {
+		_ = os.Remove(file)
+		return
+	}
+	entry.file = file
+	entry.body = nil
+	ac.memory -= entry.Size
+	return
+}
+
+func (ac *AppResponseCache) remove(entry *CachedResponse) {
+	if entry.element == nil {
+		return
+	}
+	ac.lru.Remove(entry.element)
+	entry.element = nil
+	variants := ac.entries[entry.Key]
+	for idx, found := range variants {
+		if found == entry {
+			variants = append(variants[:idx], variants[idx+1:]...)
+			break
+		}
+	}
+	if len(variants) == 0 {
+		delete(ac.entries, entry.Key)
+	} else {
+		ac.entries[entry.Key] = variants
+	}
+	ac.size -= entry.Size
+	if entry.file != "" {
+		_ = os.Remove(entry.file)
+	} else {
+		ac.memory -= entry.Size
+	}
+}
+
+type ResponseCache struct {
+	data map[string]*AppResponseCache
+
+	sync.RWMutex
+}
+
+func NewResponseCache() (rc *ResponseCache) {
+	rc = new(ResponseCache)
+	rc.data = make(map[string]*AppResponseCache)
+	return
+}
+
+func (rc *ResponseCache) Get(name string) (ac *AppResponseCache) {
+	rc.RLock()
+	defer rc.RUnlock()
+	ac = rc.data[name]
+	return
+}
+
+func (rc *ResponseCache) Replace(data map[string]*AppResponseCache) {
+	rc.Lock()
+	defer rc.Unlock()
+	rc.data = data
+}
+
+// Purge removes the app's cached responses with a request path starting with
+// the given prefix
+func (rc *ResponseCache) Purge(name, prefix string) (count int, err error) {
+	if ac := rc.Get(name); ac != nil {
+		count = ac.Purge(prefix)
+	} else {
+		err = fmt.Errorf("app cache not enabled: %v", name)
+	}
+	return
+}
+
+// reloadResponseCache updates the cache limits of all apps with caching
+// enabled, keeping their cached responses, and clears the caches of apps
+// which no longer have caching enabled
+func (rp *ReverseProxy) reloadResponseCache() {
+	rp.config.RLock()
+	root := rp.config.Paths.VarProxyCache
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+
+	rp.cache.RLock()
+	previous := rp.cache.data
+	rp.cache.RUnlock()
+
+	data := make(map[string]*AppResponseCache)
+	for _, app := range apps {
+		if !app.Cache.Enabled() {
+			continue
+		}
+		ac, ok := previous[app.Name]
+		if !ok {
+			ac = newAppResponseCache(app.Name, filepath.Join(root, app.Name))
+			// discard any overflow left by a previous reverse-proxy process
+			_ = os.RemoveAll(ac.dir)
+		}
+		ac.configure(app.Cache)
+		data[app.Name] = ac
+	}
+	for name, ac := range previous {
+		if _, ok := data[name]; !ok {
+			ac.Clear()
+		}
+	}
+	rp.cache.Replace(data)
+	if len(data) > 0 {
+		rp.LogInfoF("[cache] response cache enabled for %d apps", len(data))
+	}
+}
+
+// ServeCachedHTTP serves the request from the app's response cache if
+// possible, otherwise the request is passed to ServeOriginHTTP and any
+// cacheable response is stored
+func (rp *ReverseProxy) ServeCachedHTTP(app *Application, slugPort int, forwardFor string, w http.ResponseWriter, r *http.Request) (status int, err error) {
+	var ac *AppResponseCache
+	if ac = rp.cache.Get(app.Name); ac == nil || !isCacheableRequest(r) || getCanarySlug(r) != nil {
+		// canary responses are not cached, nor served from the cache
+		status, err = rp.ServeOriginHTTP(app, slugPort, forwardFor, w, r)
+		return
+	}
+
+	var hit *CacheHit
+	if !hasNoCacheDirective(r.Header) {
+		if hit = ac.Lookup(r); hit != nil {
+			defer hit.Close()
+			if hit.Fresh {
+				getAccessRecord(r).SetCache("hit")
+				status = serveCacheHit(w, r, hit)
+				return
+			}
+		}
+	}
+
+	getAccessRecord(r).SetCache("miss")
+	recorder := newCacheRecorder(w, r, hit, ac.maxEntrySize)
+	if status, err = rp.ServeOriginHTTP(app, slugPort, forwardFor, recorder, recorder.request); err != nil {
+		return
+	}
+
+	switch {
+	case recorder.revalidated:
+		// when no longer cacheable, the entry is removed after serving it once more
+		ac.Refresh(hit, recorder.header)
+		getAccessRecord(r).SetCache("revalidated")
+		status = serveCacheHit(w, r, hit)
+	case recorder.cacheable && !recorder.overflow:
+		ac.Store(r, recorder.status, recorder.header, recorder.body.Bytes(), recorder.expires)
+	}
+	return
+}
+
+// serveCacheHit writes the cached response, or a 304 response if the request
+// conditions match the cached validators
+func serveCacheHit(w http.ResponseWriter, r *http.Request, hit *CacheHit) (status int) {
+	header := w.Header()
+	for k, v := range hit.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set("Age", strconv.FormatInt(int64(time.Since(hit.Stored).Seconds()), 10))
+	header.Set("X-Cache", "HIT")
+
+	if isNotModified(r, hit.Header) {
+		header.Del("Content-Length")
+		status = http.StatusNotModified
+		w.WriteHeader(status)
+		return
+	}
+
+	header.Set("Content-Length", strconv.FormatInt(hit.Size, 10))
+	status = hit.Status
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead && hit.body != nil {
+		_, _ = io.Copy(w, hit.body)
+	}
+	return
+}
+
+// cacheRecorder is a http.ResponseWriter which records the origin response
+// while passing it through to the client; when revalidating a stale entry, an
+// origin 304 response is recorded and not passed through, and the body is only
+// recorded if the response headers allow the response to be cached
+type cacheRecorder struct {
+	http.ResponseWriter
+
+	request *http.Request
+
+	revalidating bool
+	revalidated  bool
+
+	status    int
+	header    http.Header
+	cacheable bool
+	expires   time.Time
+	body      bytes.Buffer
+	limit     int64
+	overflow  bool
+}
+
+func newCacheRecorder(w http.ResponseWriter, r *http.Request, stale *CacheHit, limit int64) (cr *cacheRecorder) {
+	cr = &cacheRecorder{
+		ResponseWriter: w,
+		request:        r,
+		limit:          limit,
+	}
+	if stale != nil {
+		// replace any client conditions with the cached validators
+		cr.revalidating = true
+		cr.request = r.Clone(r.Context())
+		cr.request.Header.Del("If-None-Match")
+		cr.request.Header.Del("If-Modified-Since")
+		if etag := stale.Header.Get("Etag"); etag != "" {
+			cr.request.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := stale.Header.Get("Last-Modified"); lastModified != "" {
+			cr.request.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	return
+}
+
+func (cr *cacheRecorder) WriteHeader(status int) {
+	if cr.status > 0 {
+		return
+	}
+	cr.status = status
+	cr.header = cr.ResponseWriter.Header().Clone()
+	if cr.revalidating && status == http.StatusNotModified {
+		cr.revalidated = true
+		return
+	}
+	if cr.request.Method == http.MethodGet {
+		cr.expires, cr.cacheable = getCacheExpiry(status, cr.header)
+	}
+	cr.ResponseWriter.WriteHeader(status)
+}
+
+func (cr *cacheRecorder) Write(p []byte) (n int, err error) {
+	if cr.status == 0 {
+		cr.WriteHeader(http.StatusOK)
+	}
+	if cr.revalidated {
+		n = len(p)
+		return
+	}
+	if n, err = cr.ResponseWriter.Write(p); n > 0 && cr.cacheable && !cr.overflow {
+		if int64(cr.body.Len()+n) > cr.limit {
+			cr.overflow = true
+			cr.body = bytes.Buffer{}
+		} else {
+			cr.body.Write(p[:n])
+		}
+	}
+	return
+}
+
+func (cr *cacheRecorder) FlushError() (err error) {
+	if cr.revalidated {
+		return
+	}
+	err = http.NewResponseController(cr.ResponseWriter).Flush()
+	return
+}
+
+func (cr *cacheRecorder) Unwrap() (w http.ResponseWriter) {
+	w = cr.ResponseWriter
+	return
+}
+
+func isCacheableRequest(r *http.Request) (ok bool) {
+	switch {
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+	case r.Header.Get("Authorization") != "", r.Header.Get("Range") != "":
+	case IsUpgradeRequest(r):
+	case strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-store"):
+	default:
+		ok = true
+	}
+	return
+}
+
+func hasNoCacheDirective(header http.Header) (noCache bool) {
+	noCache = strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-cache") ||
+		strings.Contains(strings.ToLower(header.Get("Pragma")), "no-cache")
+	return
+}
+
+// getCacheExpiry returns when the response becomes stale, ok is false if the
+// response is not to be cached; s-maxage takes precedence over max-age which
+// takes precedence over Expires, responses without any of these are not cached
+func getCacheExpiry(status int, header http.Header) (expires time.Time, ok bool) {
+	if _, cacheable := cacheableStatus[status]; !cacheable {
+		return
+	} else if len(header.Values("Set-Cookie")) > 0 || header.Get("Trailer") != "" {
+		return
+	} else if strings.Contains(header.Get("Vary"), "*") {
+		return
+	}
+
+	now := time.Now()
+	maxAge, sMaxAge := -1, -1
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store", "no-cache", "private":
+				return
+			case "max-age":
+				if v, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil {
+					maxAge = v
+				}
+			case "s-maxage":
+				if v, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil {
+					sMaxAge = v
+				}
+			}
+		}
+	}
+
+	switch {
+	case sMaxAge >= 0:
+		expires = now.Add(time.Duration(sMaxAge) * time.Second)
+	case maxAge >= 0:
+		expires = now.Add(time.Duration(maxAge) * time.Second)
+	case header.Get("Expires") != "":
+		exp, err := http.ParseTime(header.Get("Expires"))
+		if err != nil {
+			// invalid Expires values mean already expired
+			exp = now
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = now
+		}
+		expires = now.Add(exp.Sub(date))
+	default:
+		return
+	}
+
+	// stale responses are only useful if they can be revalidated
+	ok = expires.After(now) || header.Get("Etag") != "" || header.Get("Last-Modified") != ""
+	return
+}
+
+func getCacheKey(r *http.Request) (key string) {
+	key = strings.ToLower(r.Host) + r.URL.RequestURI()
+	return
+}
+
+// getCacheVary returns the request header values named by the response Vary
+// header
+func getCacheVary(header http.Header, r *http.Request) (vary map[string]string) {
+	vary = make(map[string]string)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				vary[name] = strings.Join(r.Header.Values(name), ", ")
+			}
+		}
+	}
+	return
+}
+
+func matchCacheVary(vary map[string]string, r *http.Request) (ok bool) {
+	for name, value := range vary {
+		if strings.Join(r.Header.Values(name), ", ") != value {
+			return
+		}
+	}
+	ok = true
+	return
+}
+
+// isNotModified returns true if the request If-None-Match or If-Modified-Since
+// conditions match the given response headers
+func isNotModified(r *http.Request, header http.Header) (notModified bool) {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := strings.TrimPrefix(header.Get("Etag"), "W/")
+		if etag == "" {
+			return
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			if candidate = strings.TrimSpace(candidate); candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				notModified = true
+				return
+			}
+		}
+		return
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if since, err := http.ParseTime(ims); err == nil {
+			if modified, ee := http.ParseTime(header.Get("Last-Modified")); ee == nil {
+				notModified = !modified.After(since)
+			}
+		}
+	}
+	return
+}
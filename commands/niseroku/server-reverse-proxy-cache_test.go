@@ -0,0 +1,179 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGetCacheKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		target string
+		expect string
+	}{
+		{"root", "example.com", "/", "example.com/"},
+		{"host case", "Example.COM", "/Page", "example.com/Page"},
+		{"host port", "example.com:8080", "/", "example.com:8080/"},
+		{"query", "example.com", "/search?q=a&p=2", "example.com/search?q=a&p=2"},
+		{"query order", "example.com", "/search?p=2&q=a", "example.com/search?p=2&q=a"},
+		{"escaped path", "example.com", "/a%2Fb", "example.com/a%2Fb"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, test.target, nil)
+			r.Host = test.host
+			if key := getCacheKey(r); key != test.expect {
+				t.Errorf("getCacheKey(%v %v) = %q, expected %q", test.host, test.target, key, test.expect)
+			}
+		})
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	tests := []struct {
+		name    string
+		vary    []string
+		stored  http.Header
+		request http.Header
+		expect  map[string]string
+		matches bool
+	}{
+		{
+			name:    "no vary",
+			stored:  http.Header{"Accept-Encoding": {"gzip"}},
+			request: http.Header{"Accept-Encoding": {"br"}},
+			expect:  map[string]string{},
+			matches: true,
+		},
+		{
+			name:    "same value",
+			vary:    []string{"Accept-Encoding"},
+			stored:  http.Header{"Accept-Encoding": {"gzip"}},
+			request: http.Header{"Accept-Encoding": {"gzip"}},
+			expect:  map[string]string{"Accept-Encoding": "gzip"},
+			matches: true,
+		},
+		{
+			name:    "different value",
+			vary:    []string{"Accept-Encoding"},
+			stored:  http.Header{"Accept-Encoding": {"gzip"}},
+			request: http.Header{"Accept-Encoding": {"br"}},
+			expect:  map[string]string{"Accept-Encoding": "gzip"},
+		},
+		{
+			name:    "missing value",
+			vary:    []string{"Accept-Language"},
+			stored:  http.Header{},
+			request: http.Header{"Accept-Language": {"en"}},
+			expect:  map[string]string{"Accept-Language": ""},
+		},
+		{
+			name:    "both missing",
+			vary:    []string{"Accept-Language"},
+			stored:  http.Header{},
+			request: http.Header{},
+			expect:  map[string]string{"Accept-Language": ""},
+			matches: true,
+		},
+		{
+			name:    "list and case",
+			vary:    []string{"accept-encoding, ACCEPT-LANGUAGE", "Origin"},
+			stored:  http.Header{"Accept-Encoding": {"gzip"}, "Accept-Language": {"en"}, "Origin": {"https://a.example"}},
+			request: http.Header{"Accept-Encoding": {"gzip"}, "Accept-Language": {"en"}, "Origin": {"https://b.example"}},
+			expect:  map[string]string{"Accept-Encoding": "gzip", "Accept-Language": "en", "Origin": "https://a.example"},
+		},
+		{
+			name:    "multiple values",
+			vary:    []string{"Accept"},
+			stored:  http.Header{"Accept": {"text/html", "*/*"}},
+			request: http.Header{"Accept": {"text/html, */*"}},
+			expect:  map[string]string{"Accept": "text/html, */*"},
+			matches: true,
+		},
+		{
+			name:    "empty names",
+			vary:    []string{" , Accept ,"},
+			stored:  http.Header{"Accept": {"text/html"}},
+			request: http.Header{"Accept": {"text/html"}},
+			expect:  map[string]string{"Accept": "text/html"},
+			matches: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := http.Header{"Vary": test.vary}
+			stored := httptest.NewRequest(http.MethodGet, "/", nil)
+			stored.Header = test.stored
+			vary := getCacheVary(response, stored)
+			if !reflect.DeepEqual(vary, test.expect) {
+				t.Errorf("getCacheVary(%v) = %v, expected %v", test.vary, vary, test.expect)
+			}
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header = test.request
+			if matches := matchCacheVary(vary, request); matches != test.matches {
+				t.Errorf("matchCacheVary(%v, %v) = %v, expected %v", vary, test.request, matches, test.matches)
+			}
+		})
+	}
+}
+
+func TestCacheRecorder(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		header    http.Header
+		limit     int64
+		cacheable bool
+		recorded  string
+	}{
+		{"max-age", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, 1024, true, "hello"},
+		{"public s-maxage", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"public, s-maxage=60"}}, 1024, true, "hello"},
+		{"no-store", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"no-store"}}, 1024, false, ""},
+		{"private", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"private, max-age=60"}}, 1024, false, ""},
+		{"set-cookie", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}, "Set-Cookie": {"id=1"}}, 1024, false, ""},
+		{"no expiry", http.MethodGet, http.StatusOK, http.Header{}, 1024, false, ""},
+		{"uncacheable status", http.MethodGet, http.StatusInternalServerError, http.Header{"Cache-Control": {"max-age=60"}}, 1024, false, ""},
+		{"head request", http.MethodHead, http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, 1024, false, ""},
+		{"too large", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, 2, true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(test.method, "/", nil)
+			cr := newCacheRecorder(w, r, nil, test.limit)
+			for k, v := range test.header {
+				cr.Header()[k] = v
+			}
+			cr.WriteHeader(test.status)
+			_, _ = cr.Write([]byte("hel"))
+			_, _ = cr.Write([]byte("lo"))
+			if cr.cacheable != test.cacheable {
+				t.Errorf("cacheable = %v, expected %v", cr.cacheable, test.cacheable)
+			}
+			if recorded := cr.body.String(); recorded != test.recorded {
+				t.Errorf("recorded body = %q, expected %q", recorded, test.recorded)
+			}
+			if w.Code != test.status || w.Body.String() != "hello" {
+				t.Errorf("client response = %d %q, expected %d %q", w.Code, w.Body.String(), test.status, "hello")
+			}
+		})
+	}
+}
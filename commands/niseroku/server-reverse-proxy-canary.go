@@ -0,0 +1,295 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type canarySlugKey struct{}
+
+// CanaryState is the progress of one app's canary deployment, the decision
+// is acted upon by the deploy process which is waiting for it
+type CanaryState struct {
+	App          string
+	Slug         string
+	Percent      int
+	Settings     *AppCanary
+	Started      time.Time
+	Duration     time.Duration
+	MinRequests  int64
+	MaxErrorRate float64
+
+	BaselineRequests int64
+	BaselineErrors   int64
+	CanaryRequests   int64
+	CanaryErrors     int64
+
+	Decision string
+	Reason   string
+}
+
+// Record counts the response status of a this-slug or canary request and
+// returns the decision if this response caused one
+func (s *CanaryState) Record(canary bool, status int) (decided string) {
+	failed := status >= 500
+	if canary {
+		s.CanaryRequests += 1
+		if failed {
+			s.CanaryErrors += 1
+		}
+	} else {
+		s.BaselineRequests += 1
+		if failed {
+			s.BaselineErrors += 1
+		}
+	}
+	decided = s.evaluate()
+	return
+}
+
+// evaluate compares the 5xx rates once there are enough canary requests,
+// aborting if the canary is worse and promoting once the duration elapsed
+func (s *CanaryState) evaluate() (decided string) {
+	if s.Decision != "" || s.CanaryRequests < s.MinRequests {
+		return
+	}
+	canaryRate := float64(s.CanaryErrors) / float64(s.CanaryRequests)
+	var baselineRate float64
+	if s.BaselineRequests > 0 {
+		baselineRate = float64(s.BaselineErrors) / float64(s.BaselineRequests)
+	}
+	if canaryRate > baselineRate+s.MaxErrorRate {
+		s.Decide(CanaryDecisionAbort, fmt.Sprintf("canary 5xx rate %.3f exceeds this-slug rate %.3f", canaryRate, baselineRate))
+	} else if time.Since(s.Started) >= s.Duration {
+		s.Decide(CanaryDecisionPromote, fmt.Sprintf("canary 5xx rate %.3f within this-slug rate %.3f", canaryRate, baselineRate))
+	}
+	decided = s.Decision
+	return
+}
+
+// Decide records the decision, returning false if there already was one
+func (s *CanaryState) Decide(decision, reason string) (decided bool) {
+	if decided = s.Decision == ""; decided {
+		s.Decision = decision
+		s.Reason = reason
+	}
+	return
+}
+
+func (s *CanaryState) Info() (info ProxyControlCanary) {
+	info = ProxyControlCanary{
+		App:              s.App,
+		Slug:             s.Slug,
+		Active:           true,
+		Percent:          s.Percent,
+		Started:          s.Started,
+		Duration:         s.Duration.String(),
+		BaselineRequests: s.BaselineRequests,
+		BaselineErrors:   s.BaselineErrors,
+		CanaryRequests:   s.CanaryRequests,
+		CanaryErrors:     s.CanaryErrors,
+		Decision:         s.Decision,
+		Reason:           s.Reason,
+	}
+	return
+}
+
+type Canaries struct {
+	data map[string]*CanaryState
+
+	sync.RWMutex
+}
+
+func NewCanaries() (c *Canaries) {
+	c = new(Canaries)
+	c.data = make(map[string]*CanaryState)
+	return
+}
+
+// getCanarySlug returns the next slug if the request was selected for the
+// app's canary
+func getCanarySlug(r *http.Request) (slug *Slug) {
+	slug, _ = r.Context().Value(canarySlugKey{}).(*Slug)
+	return
+}
+
+// getRequestSlug returns the slug selected to serve the request
+func (rp *ReverseProxy) getRequestSlug(app *Application, r *http.Request) (slug *Slug) {
+	if slug = getCanarySlug(r); slug == nil {
+		slug = app.GetThisSlug()
+	}
+	return
+}
+
+// routeCanary selects requests for the app's canary, returning the request
+// to use for the rest of the request handling
+func (rp *ReverseProxy) routeCanary(app *Application, r *http.Request) (modified *http.Request) {
+	modified = r
+	if app == nil {
+		return
+	}
+	rp.canaries.RLock()
+	state, ok := rp.canaries.data[app.Name]
+	var selected bool
+	if ok && state.Decision == "" {
+		selected = state.Settings.Matches(r) || (state.Percent > 0 && rand.Intn(100) < state.Percent)
+	}
+	rp.canaries.RUnlock()
+	if !selected {
+		return
+	}
+	if slug := app.GetNextSlug(); slug != nil && slug.Name == state.Slug {
+		modified = r.WithContext(context.WithValue(r.Context(), canarySlugKey{}, slug))
+	}
+	return
+}
+
+// recordCanary counts the response of the app's request towards the canary
+// decision
+func (rp *ReverseProxy) recordCanary(app *Application, r *http.Request, status int) {
+	if app == nil {
+		return
+	}
+	// most requests are for apps without a canary, check before blocking
+	rp.canaries.RLock()
+	state, ok := rp.canaries.data[app.Name]
+	active := ok && state.Decision == ""
+	rp.canaries.RUnlock()
+	if !active {
+		return
+	}
+	rp.canaries.Lock()
+	defer rp.canaries.Unlock()
+	if current, ok := rp.canaries.data[app.Name]; ok && current == state && state.Decision == "" {
+		if decision := state.Record(getCanarySlug(r) != nil, status); decision != "" {
+			rp.logCanaryDecision(state)
+		}
+	}
+}
+
+func (rp *ReverseProxy) logCanaryDecision(state *CanaryState) {
+	rp.metrics.Inc("niseroku_canary_decisions_total", "app", state.App, "decision", state.Decision)
+	rp.LogInfoF("[canary] %v decided to %v: %v - %v\n", state.App, state.Decision, state.Slug, state.Reason)
+}
+
+// controlCanary performs the canary action given, returning the canary state
+func (rp *ReverseProxy) controlCanary(args ProxyControlCanaryArgs) (info ProxyControlCanary, err error) {
+	rp.config.RLock()
+	app, ok := rp.config.Applications[args.App]
+	rp.config.RUnlock()
+	if !ok {
+		err = fmt.Errorf("app not found: %v", args.App)
+		return
+	}
+
+	if args.Action == CanaryActionStart {
+		// the next slug workers were started by the deploy process
+		if err = rp.ReloadApp(args.App); err != nil {
+			return
+		}
+		rp.config.RLock()
+		app, ok = rp.config.Applications[args.App]
+		rp.config.RUnlock()
+		if !ok {
+			err = fmt.Errorf("app not found: %v", args.App)
+			return
+		}
+	}
+
+	rp.canaries.Lock()
+	defer rp.canaries.Unlock()
+	state, found := rp.canaries.data[args.App]
+
+	switch args.Action {
+	case CanaryActionStart:
+		slug := app.GetNextSlug()
+		if slug == nil || slug.Name != filepath.Base(args.Slug) {
+			err = fmt.Errorf("canary slug is not the next slug: %v", args.Slug)
+			return
+		} else if found && state.Slug == slug.Name {
+			// the deploy process is resuming after a reverse-proxy reload
+			break
+		}
+		state = &CanaryState{
+			App:          app.Name,
+			Slug:         slug.Name,
+			Percent:      app.Canary.Percent,
+			Settings:     app.Canary,
+			Started:      time.Now(),
+			Duration:     app.Canary.GetDuration(),
+			MinRequests:  int64(app.Canary.GetMinRequests()),
+			MaxErrorRate: app.Canary.GetMaxErrorRate(),
+		}
+		rp.canaries.data[app.Name] = state
+		found = true
+		rp.LogInfoF("[canary] %v started with %d%% of requests: %v\n", app.Name, state.Percent, state.Slug)
+
+	case CanaryActionEnd:
+		if found {
+			delete(rp.canaries.data, app.Name)
+			rp.LogInfoF("[canary] %v ended: %v\n", app.Name, state.Slug)
+			info = state.Info()
+			info.Active = false
+		}
+		return
+
+	case CanaryActionStatus:
+
+	case CanaryActionPercent, CanaryActionPromote, CanaryActionAbort:
+		if !found {
+			err = fmt.Errorf("%v canary not in progress", app.Name)
+			return
+		}
+		switch args.Action {
+		case CanaryActionPercent:
+			if args.Percent < 0 || args.Percent > 100 {
+				err = fmt.Errorf("canary percent must be between 0 and 100")
+				return
+			}
+			state.Percent = args.Percent
+			rp.LogInfoF("[canary] %v now given %d%% of requests: %v\n", app.Name, state.Percent, state.Slug)
+		case CanaryActionPromote:
+			if state.Decide(CanaryDecisionPromote, "promoted by operator") {
+				rp.logCanaryDecision(state)
+			}
+		case CanaryActionAbort:
+			if state.Decide(CanaryDecisionAbort, "aborted by operator") {
+				rp.logCanaryDecision(state)
+			}
+		}
+
+	default:
+		err = fmt.Errorf("unknown canary action: %q", args.Action)
+		return
+	}
+
+	if found {
+		if state.Decision == "" && state.evaluate() != "" {
+			// promotions are time based and may be due without new requests
+			rp.logCanaryDecision(state)
+		}
+		info = state.Info()
+	} else {
+		info.App = app.Name
+	}
+	return
+}
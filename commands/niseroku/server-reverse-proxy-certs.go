@@ -0,0 +1,111 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type StaticCerts struct {
+	data map[string]*tls.Certificate
+
+	sync.RWMutex
+}
+
+func NewStaticCerts() (sc *StaticCerts) {
+	sc = new(StaticCerts)
+	sc.data = make(map[string]*tls.Certificate)
+	return
+}
+
+// Lookup returns the certificate for the exact server name, or for the
+// wildcard of its parent domain
+func (sc *StaticCerts) Lookup(serverName string) (cert *tls.Certificate) {
+	sc.RLock()
+	defer sc.RUnlock()
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if found, ok := sc.data[serverName]; ok {
+		cert = found
+		return
+	}
+	if idx := strings.Index(serverName, "."); idx > 0 {
+		if found, ok := sc.data["*"+serverName[idx:]]; ok {
+			cert = found
+		}
+	}
+	return
+}
+
+func (sc *StaticCerts) Replace(data map[string]*tls.Certificate) {
+	sc.Lock()
+	defer sc.Unlock()
+	sc.data = data
+}
+
+// reloadStaticCerts loads all app certificate files, replacing the previously
+// loaded certificates; any certificates that fail to load are logged and
+// skipped so that one bad app.toml cannot disable https for the others
+func (rp *ReverseProxy) reloadStaticCerts() {
+	rp.config.RLock()
+	secrets := rp.config.Paths.ProxySecrets
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+
+	data := make(map[string]*tls.Certificate)
+	owners := make(map[string]string)
+	for _, app := range apps {
+		for _, ac := range app.Certificates {
+			domain := strings.ToLower(ac.Domain)
+			if owner, exists := owners[domain]; exists {
+				rp.LogErrorF("[certs] %v certificate for %v already provided by %v", app.Name, domain, owner)
+				continue
+			}
+			certFile, keyFile, err := ac.GetPaths(secrets)
+			if err != nil {
+				rp.LogErrorF("[certs] %v certificate for %v: %v", app.Name, domain, err)
+				continue
+			}
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				rp.LogErrorF("[certs] error loading %v certificate for %v: %v", app.Name, domain, err)
+				continue
+			}
+			data[domain] = &cert
+			owners[domain] = app.Name
+		}
+	}
+
+	rp.certs.Replace(data)
+	rp.LogInfoF("[certs] loaded %d static certificates", len(data))
+}
+
+// getCertificate prefers static certificates and falls back to autocert
+func (rp *ReverseProxy) getCertificate(hello *tls.ClientHelloInfo) (cert *tls.Certificate, err error) {
+	if cert = rp.certs.Lookup(hello.ServerName); cert != nil {
+		return
+	}
+	if m := rp.getAutocert(); m != nil {
+		cert, err = m.GetCertificate(hello)
+		return
+	}
+	err = fmt.Errorf("certificate not found: %q", hello.ServerName)
+	return
+}
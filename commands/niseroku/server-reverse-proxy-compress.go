@@ -0,0 +1,218 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/go-enjin/be/pkg/net/serve"
+)
+
+var (
+	gzipWriterPool = sync.Pool{
+		New: func() any {
+			gw, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return gw
+		},
+	}
+	zstdEncoderPool = sync.Pool{
+		New: func() any {
+			zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return zw
+		},
+	}
+)
+
+// getCompression returns the global [compression] settings merged with any
+// app-specific settings
+func (rp *ReverseProxy) getCompression(app *Application) (settings CompressionConfig) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	settings = rp.config.Compression.Merge(app.Compression)
+	return
+}
+
+// negotiateCompression returns the content-encoding to use for the given
+// origin response, or an empty string if the response is not to be
+// compressed; must be called after the response headers are copied to w and
+// before w.WriteHeader
+func (rp *ReverseProxy) negotiateCompression(app *Application, w http.ResponseWriter, r *http.Request, response *http.Response) (encoding string) {
+	settings := rp.getCompression(app)
+	if !settings.IsEnabled() {
+		return
+	}
+
+	header := w.Header()
+	switch {
+	case r.Method == http.MethodHead:
+		return
+	case !serve.StatusHasBody(response.StatusCode), response.StatusCode == http.StatusPartialContent:
+		return
+	case header.Get("Content-Encoding") != "", header.Get("Content-Range") != "":
+		// already encoded or a byte range of the identity encoding
+		return
+	case strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-transform"):
+		return
+	}
+
+	contentType := header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType == "text/event-stream" {
+		return
+	} else if !settings.AllowsContentType(contentType) {
+		return
+	}
+	if response.ContentLength >= 0 && response.ContentLength < settings.GetMinSize() {
+		return
+	}
+
+	// the response varies by Accept-Encoding even when the client accepts none
+	header.Add("Vary", "Accept-Encoding")
+
+	if encoding = selectEncoding(r.Header.Values("Accept-Encoding"), settings.Encodings); encoding == "" {
+		return
+	}
+
+	header.Set("Content-Encoding", encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	if etag := header.Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		// the compressed representation is not byte-for-byte identical
+		header.Set("Etag", "W/"+etag)
+	}
+	return
+}
+
+// selectEncoding returns the supported encoding with the highest Accept-Encoding
+// quality value, ties are broken by the order of the supported encodings
+func selectEncoding(acceptEncoding []string, supported []string) (encoding string) {
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, value := range acceptEncoding {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+				continue
+			}
+			quality := 1.0
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+			if name == "*" {
+				wildcard = quality
+			} else {
+				qualities[name] = quality
+			}
+		}
+	}
+
+	best := 0.0
+	for _, name := range supported {
+		quality, ok := qualities[name]
+		if !ok {
+			quality = wildcard
+		}
+		if quality > best {
+			best = quality
+			encoding = name
+		}
+	}
+	return
+}
+
+// compressWriter is a http.ResponseWriter which encodes all body writes,
+// flushing the encoder before flushing the underlying ResponseWriter so that
+// streamed responses are delivered as they are written
+type compressWriter struct {
+	http.ResponseWriter
+
+	rc       *http.ResponseController
+	encoding string
+	encoder  io.WriteCloser
+	closed   bool
+}
+
+func newCompressWriter(w http.ResponseWriter, encoding string) (cw *compressWriter) {
+	cw = &compressWriter{
+		ResponseWriter: w,
+		rc:             http.NewResponseController(w),
+		encoding:       encoding,
+	}
+	switch encoding {
+	case EncodingZstd:
+		zw := zstdEncoderPool.Get().(*zstd.Encoder)
+		zw.Reset(w)
+		cw.encoder = zw
+	default:
+		gw := gzipWriterPool.Get().(*gzip.Writer)
+		gw.Reset(w)
+		cw.encoder = gw
+	}
+	return
+}
+
+func (cw *compressWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.encoder.Write(p)
+	return
+}
+
+func (cw *compressWriter) FlushError() (err error) {
+	if cw.closed {
+		return
+	}
+	switch encoder := cw.encoder.(type) {
+	case *zstd.Encoder:
+		err = encoder.Flush()
+	case *gzip.Writer:
+		err = encoder.Flush()
+	}
+	if err == nil {
+		err = cw.rc.Flush()
+	}
+	return
+}
+
+func (cw *compressWriter) Unwrap() (w http.ResponseWriter) {
+	w = cw.ResponseWriter
+	return
+}
+
+// Close writes the remainder of the encoded body and returns the encoder to
+// its pool, Close must be called before any trailers are set
+func (cw *compressWriter) Close() (err error) {
+	if cw.closed {
+		return
+	}
+	cw.closed = true
+	err = cw.encoder.Close()
+	switch encoder := cw.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		zstdEncoderPool.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(nil)
+		gzipWriterPool.Put(encoder)
+	}
+	return
+}
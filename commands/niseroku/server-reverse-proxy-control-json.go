@@ -0,0 +1,305 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+
+	"github.com/go-corelibs/maps"
+)
+
+// proxyControlJsonCommands are the JSON protocol commands, all other commands
+// are passed to the text protocol with the argv of ProxyControlTextArgs and
+// the text output is returned as a JSON string
+var proxyControlJsonCommands = []string{
+	"info",
+	"apps",
+	"routes",
+	"workers",
+	"maintenance",
+	"drain",
+	"reload-app",
+	"canary",
+	"mirrors",
+	"stats",
+}
+
+// handleSockJson processes newline-delimited JSON requests until the client
+// closes the connection
+func (rp *ReverseProxy) handleSockJson(conn net.Conn, reader *bufio.Reader) {
+	decoder := json.NewDecoder(reader)
+	encoder := json.NewEncoder(conn)
+	for {
+		var request ProxyControlRequest
+		if err := decoder.Decode(&request); err != nil {
+			if !errors.Is(err, io.EOF) {
+				rp.LogErrorF("invalid json control request: %v\n", err)
+				_ = encoder.Encode(ProxyControlResponse{
+					Version: ProxyControlProtocolVersion,
+					Error:   fmt.Sprintf("invalid request: %v", err),
+				})
+			}
+			return
+		}
+		if err := encoder.Encode(rp.processControlRequest(&request)); err != nil {
+			rp.LogErrorF("error writing json control response: %v\n", err)
+			return
+		}
+	}
+}
+
+func (rp *ReverseProxy) processControlRequest(request *ProxyControlRequest) (response ProxyControlResponse) {
+	response.Version = ProxyControlProtocolVersion
+	response.Id = request.Id
+
+	var err error
+	var data interface{}
+	if request.Version > ProxyControlProtocolVersion {
+		err = fmt.Errorf("unsupported protocol version: %d", request.Version)
+	} else {
+		data, err = rp.processControlCommand(request.Command, request.Args)
+	}
+
+	if err == nil && data != nil {
+		response.Data, err = json.Marshal(data)
+	}
+	if err != nil {
+		response.Error = err.Error()
+		return
+	}
+	response.Ok = true
+	return
+}
+
+func (rp *ReverseProxy) processControlCommand(command string, raw json.RawMessage) (data interface{}, err error) {
+	decode := func(args interface{}) (err error) {
+		if len(raw) == 0 {
+			err = fmt.Errorf("%v requires args", command)
+		} else if err = json.Unmarshal(raw, args); err != nil {
+			err = fmt.Errorf("invalid %v args: %v", command, err)
+		}
+		return
+	}
+
+	switch command {
+
+	case "info":
+		data = ProxyControlInfo{
+			Version:  ProxyControlProtocolVersion,
+			Commands: proxyControlJsonCommands,
+		}
+
+	case "apps":
+		data = rp.controlListApps()
+
+	case "routes":
+		data = rp.controlListRoutes()
+
+	case "workers":
+		var args ProxyControlAppArgs
+		if len(raw) > 0 {
+			if err = decode(&args); err != nil {
+				return
+			}
+		}
+		data, err = rp.controlListWorkers(args.App)
+
+	case "maintenance":
+		var args ProxyControlMaintenanceArgs
+		if err = decode(&args); err == nil {
+			err = rp.setMaintenance(args.App, args.Enable)
+		}
+
+	case "drain":
+		var args ProxyControlDrainArgs
+		if err = decode(&args); err == nil {
+			err = rp.drainWorker(args.App, args.Port, args.Drain)
+		}
+
+	case "reload-app":
+		var args ProxyControlAppArgs
+		if err = decode(&args); err == nil {
+			err = rp.ReloadApp(args.App)
+		}
+
+	case "canary":
+		var args ProxyControlCanaryArgs
+		if err = decode(&args); err == nil {
+			data, err = rp.controlCanary(args)
+		}
+
+	case "mirrors":
+		var args ProxyControlAppArgs
+		if len(raw) > 0 {
+			if err = decode(&args); err != nil {
+				return
+			}
+		}
+		data = rp.mirrors.List(args.App)
+
+	case "stats":
+		var args ProxyControlStatsArgs
+		if len(raw) > 0 {
+			if err = decode(&args); err != nil {
+				return
+			}
+		}
+		data, err = rp.controlStats(args)
+
+	default:
+		var args ProxyControlTextArgs
+		if len(raw) > 0 {
+			if err = decode(&args); err != nil {
+				return
+			}
+		}
+		data, err = rp.controlSocketProcessCommand(command, args.Argv)
+	}
+	return
+}
+
+func (rp *ReverseProxy) controlListApps() (apps []ProxyControlApp) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	apps = make([]ProxyControlApp, 0, len(rp.config.Applications))
+	for _, name := range maps.SortedKeys(rp.config.Applications) {
+		app := rp.config.Applications[name]
+		info := ProxyControlApp{
+			Name:        app.Name,
+			Domains:     app.Domains,
+			Maintenance: app.Maintenance,
+			ThisSlug:    app.ThisSlug,
+			NextSlug:    app.NextSlug,
+			LivePorts:   []int{},
+		}
+		if slug := app.GetThisSlug(); slug != nil {
+			info.LivePorts = append(info.LivePorts, slug.GetLivePorts()...)
+		}
+		apps = append(apps, info)
+	}
+	return
+}
+
+func (rp *ReverseProxy) controlListRoutes() (routes ProxyControlRoutes) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	routes.Routes = []ProxyControlRoute{}
+	routes.Redirects = []ProxyControlRedirect{}
+	for _, host := range maps.SortedKeys(rp.config.RouteLookup) {
+		for _, route := range rp.config.RouteLookup[host] {
+			routes.Routes = append(routes.Routes, ProxyControlRoute{
+				Host:   route.Host,
+				Prefix: route.Prefix,
+				App:    route.App.Name,
+			})
+		}
+	}
+	for _, host := range maps.SortedKeys(rp.config.RedirectLookup) {
+		redirect := rp.config.RedirectLookup[host]
+		info := ProxyControlRedirect{
+			FromHost: host,
+			ToHost:   redirect.ToHost,
+			Status:   redirect.GetStatus(),
+		}
+		if app, ok := rp.config.DomainLookup[host]; ok {
+			info.App = app.Name
+		}
+		routes.Redirects = append(routes.Redirects, info)
+	}
+	return
+}
+
+// controlListWorkers returns the live worker state of the named app, or of all
+// apps if name is empty
+func (rp *ReverseProxy) controlListWorkers(name string) (workers []ProxyControlWorker, err error) {
+	rp.config.RLock()
+	var apps []*Application
+	if name != "" {
+		if app, ok := rp.config.Applications[name]; ok {
+			apps = append(apps, app)
+		} else {
+			err = fmt.Errorf("app not found: %v", name)
+		}
+	} else {
+		for _, app := range rp.config.Applications {
+			apps = append(apps, app)
+		}
+	}
+	rp.config.RUnlock()
+	if err != nil {
+		return
+	}
+
+	workers = []ProxyControlWorker{}
+	for _, app := range apps {
+		slug := app.GetThisSlug()
+		if slug == nil {
+			continue
+		}
+		for _, worker := range slug.GetLiveWorkers() {
+			inFlight := rp.PortInFlight(worker.Port)
+			if inFlight < 0 {
+				inFlight = 0
+			}
+			workers = append(workers, ProxyControlWorker{
+				App:         app.Name,
+				Slug:        slug.Name,
+				Hash:        worker.Hash,
+				Port:        worker.Port,
+				Running:     slug.IsRunning(worker.Hash),
+				Ready:       slug.IsReady(worker.Hash),
+				Healthy:     rp.PortIsHealthy(worker.Port),
+				Draining:    rp.PortIsDraining(worker.Port),
+				CircuitOpen: rp.PortCircuitOpen(worker.Port),
+				InFlight:    inFlight,
+			})
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].App == workers[j].App {
+			return workers[i].Port < workers[j].Port
+		}
+		return workers[i].App < workers[j].App
+	})
+	return
+}
+
+// setMaintenance updates the maintenance setting of the named app.toml and
+// reloads the app
+func (rp *ReverseProxy) setMaintenance(name string, enable bool) (err error) {
+	rp.config.RLock()
+	app, ok := rp.config.Applications[name]
+	rp.config.RUnlock()
+	if !ok {
+		err = fmt.Errorf("app not found: %v", name)
+		return
+	}
+	app.Lock()
+	app.Maintenance = enable
+	app.Unlock()
+	if err = app.Save(true); err != nil {
+		err = fmt.Errorf("error saving %v app.toml: %v", name, err)
+		return
+	}
+	rp.LogInfoF("[control] app maintenance mode set to %v: %v\n", enable, name)
+	err = rp.ReloadApp(name)
+	return
+}
@@ -15,6 +15,7 @@
 package niseroku
 
 import (
+	"bufio"
 	"encoding/csv"
 	"fmt"
 	"net"
@@ -49,8 +50,16 @@ func (rp *ReverseProxy) HandleSock(conn net.Conn) {
 	defer func() { _ = conn.Close() }()
 	var err error
 	var bufRead int
+	reader := bufio.NewReader(conn)
+	if first, ee := reader.Peek(1); ee != nil {
+		rp.LogErrorF("error reading control socket: %v\n", ee)
+		return
+	} else if first[0] == '{' {
+		rp.handleSockJson(conn, reader)
+		return
+	}
 	buf := make([]byte, 1024)
-	if bufRead, err = conn.Read(buf); err != nil {
+	if bufRead, err = reader.Read(buf); err != nil {
 		rp.LogErrorF("error reading control socket: %v\n", err)
 		return
 	}
@@ -102,6 +111,44 @@ func (rp *ReverseProxy) controlSocketProcessCommand(cmd string, argv []string) (
 		// rp.LogInfoF("[control] processed command: %v %v\n", cmd, argv)
 		return
 
+	case "health-checks":
+		out = strings.TrimSpace(rp.health.String())
+		return
+
+	case "renew-cert":
+		if len(argv) != 1 {
+			err = fmt.Errorf("renew-cert requires one domain argument")
+			return
+		}
+		if err = rp.renewCertificate(argv[0]); err == nil {
+			out = fmt.Sprintf("renewing certificate: %v", argv[0])
+		}
+		return
+
+	case "cache-purge":
+		if len(argv) < 1 || len(argv) > 2 {
+			err = fmt.Errorf("cache-purge requires an app name and optional path prefix")
+			return
+		}
+		var prefix string
+		if len(argv) == 2 {
+			prefix = argv[1]
+		}
+		var count int
+		if count, err = rp.cache.Purge(argv[0], prefix); err == nil {
+			out = fmt.Sprintf("purged %d cached responses: %v%v", count, argv[0], prefix)
+			rp.LogInfoF("[control] %v\n", out)
+		}
+		return
+
+	case "metrics":
+		out = strings.TrimSpace(rp.metricsText())
+		return
+
+	case "metrics-event":
+		err = rp.recordMetricsEvent(argv)
+		return
+
 	case "nop":
 		out = fmt.Sprintf("[control] processed command: %v %v", cmd, argv)
 		rp.LogInfoF("%v\n", out)
@@ -0,0 +1,123 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WorkerDrains tracks the worker ports excluded from live port selection, the
+// worker hash is kept so that a new worker reusing the port is not drained
+type WorkerDrains struct {
+	data map[int]string
+
+	sync.RWMutex
+}
+
+func NewWorkerDrains() (d *WorkerDrains) {
+	d = new(WorkerDrains)
+	d.data = make(map[int]string)
+	return
+}
+
+func (d *WorkerDrains) IsDraining(port int) (draining bool) {
+	d.RLock()
+	defer d.RUnlock()
+	_, draining = d.data[port]
+	return
+}
+
+func (d *WorkerDrains) Drain(port int, hash string) {
+	d.Lock()
+	defer d.Unlock()
+	d.data[port] = hash
+}
+
+func (d *WorkerDrains) Undrain(port int) (found bool) {
+	d.Lock()
+	defer d.Unlock()
+	if _, found = d.data[port]; found {
+		delete(d.data, port)
+	}
+	return
+}
+
+// Prune removes all drained ports no longer used by the same worker
+func (d *WorkerDrains) Prune(live map[int]string) {
+	d.Lock()
+	defer d.Unlock()
+	for port, hash := range d.data {
+		if current, ok := live[port]; !ok || current != hash {
+			delete(d.data, port)
+		}
+	}
+}
+
+func (rp *ReverseProxy) PortIsDraining(port int) (draining bool) {
+	draining = rp.drains.IsDraining(port)
+	return
+}
+
+// drainWorker excludes (or includes again) the app's live worker on the given
+// port from live port selection, in-flight requests are not interrupted
+func (rp *ReverseProxy) drainWorker(name string, port int, drain bool) (err error) {
+	rp.config.RLock()
+	app, ok := rp.config.Applications[name]
+	rp.config.RUnlock()
+	if !ok {
+		err = fmt.Errorf("app not found: %v", name)
+		return
+	}
+	var slug *Slug
+	if slug = app.GetThisSlug(); slug == nil {
+		err = fmt.Errorf("app slug not found: %v", name)
+		return
+	}
+	for _, worker := range slug.GetLiveWorkers() {
+		if worker.Port == port {
+			if drain {
+				rp.drains.Drain(port, worker.Hash)
+				rp.LogInfoF("[drain] worker draining: %v [%v] on port %d\n", app.Name, worker.Hash, port)
+			} else if rp.drains.Undrain(port) {
+				rp.LogInfoF("[drain] worker restored: %v [%v] on port %d\n", app.Name, worker.Hash, port)
+			}
+			return
+		}
+	}
+	err = fmt.Errorf("app worker not found: %v on port %d", name, port)
+	return
+}
+
+// pruneDrains removes drained ports and circuit breakers no longer used by
+// the same live worker
+func (rp *ReverseProxy) pruneDrains() {
+	rp.config.RLock()
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+	live := make(map[int]string)
+	for _, app := range apps {
+		if slug := app.GetThisSlug(); slug != nil {
+			for _, worker := range slug.GetLiveWorkers() {
+				live[worker.Port] = worker.Hash
+			}
+		}
+	}
+	rp.drains.Prune(live)
+	rp.breakers.Prune(live)
+}
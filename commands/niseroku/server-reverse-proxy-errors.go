@@ -0,0 +1,166 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"html/template"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/kataras/requestid"
+
+	"github.com/go-enjin/be/pkg/net/serve"
+)
+
+type ErrorPageData struct {
+	Status     int
+	StatusText string
+	App        string
+	Host       string
+	Path       string
+	RequestId  string
+	RetryAfter int
+}
+
+type ErrorPages struct {
+	data map[string]map[int]*template.Template
+
+	sync.RWMutex
+}
+
+func NewErrorPages() (ep *ErrorPages) {
+	ep = new(ErrorPages)
+	ep.data = make(map[string]map[int]*template.Template)
+	return
+}
+
+func (ep *ErrorPages) Lookup(app string, status int) (tmpl *template.Template) {
+	ep.RLock()
+	defer ep.RUnlock()
+	if pages, ok := ep.data[app]; ok {
+		tmpl = pages[status]
+	}
+	return
+}
+
+func (ep *ErrorPages) Replace(data map[string]map[int]*template.Template) {
+	ep.Lock()
+	defer ep.Unlock()
+	ep.data = data
+}
+
+// reloadErrorPages parses all app error page templates, replacing the
+// previously loaded templates; pages that fail to parse are logged and
+// skipped so that the default error pages are used instead
+func (rp *ReverseProxy) reloadErrorPages() {
+	rp.config.RLock()
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+
+	var count int
+	data := make(map[string]map[int]*template.Template)
+	for _, app := range apps {
+		dir := app.ErrorPages.GetDirectory(app)
+		var found []string
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					found = append(found, entry.Name())
+				}
+			}
+		} else if app.ErrorPages == nil {
+			// error pages are optional unless configured
+			continue
+		} else {
+			rp.LogErrorF("[error-pages] error reading %v error pages: %v", app.Name, err)
+			continue
+		}
+		pages := make(map[int]*template.Template)
+		for status, file := range app.ErrorPages.GetFiles(found) {
+			path := filepath.Join(dir, file)
+			if tmpl, err := template.ParseFiles(path); err != nil {
+				rp.LogErrorF("[error-pages] error parsing %v error page: %v - %v", app.Name, path, err)
+			} else {
+				pages[status] = tmpl
+				count += 1
+			}
+		}
+		if len(pages) > 0 {
+			data[app.Name] = pages
+		}
+	}
+
+	rp.errorPages.Replace(data)
+	rp.LogInfoF("[error-pages] loaded %d custom error pages", count)
+}
+
+// serveError writes the app's custom error page for the status given, or the
+// default error page if the app does not have one
+func (rp *ReverseProxy) serveError(w http.ResponseWriter, r *http.Request, app *Application, status int) {
+	var retryAfter int
+	if status == http.StatusServiceUnavailable {
+		var settings *AppErrorPages
+		if app != nil {
+			settings = app.ErrorPages
+		}
+		retryAfter = int(math.Ceil(settings.GetRetryAfter().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	if app != nil {
+		if tmpl := rp.errorPages.Lookup(app.Name, status); tmpl != nil {
+			domain, _, _ := rp.GetAppDomain(r)
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, ErrorPageData{
+				Status:     status,
+				StatusText: http.StatusText(status),
+				App:        app.Name,
+				Host:       domain,
+				Path:       r.URL.Path,
+				RequestId:  requestid.Get(r),
+				RetryAfter: retryAfter,
+			}); err != nil {
+				rp.LogErrorF("[error-pages] error rendering %v %d page: %v", app.Name, status, err)
+			} else {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(status)
+				_, _ = w.Write(buf.Bytes())
+				return
+			}
+		}
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		serve.Serve404(w, r)
+	case http.StatusBadGateway:
+		serve.Serve502(w, r)
+	case http.StatusServiceUnavailable:
+		serve.Serve503(w, r)
+	case http.StatusInternalServerError:
+		serve.Serve500(w, r)
+	default:
+		http.Error(w, strconv.Itoa(status)+" "+http.StatusText(status), status)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ForwardedHop is one proxy hop of the X-Forwarded-For or Forwarded request
+// headers
+type ForwardedHop struct {
+	For   string
+	Proto string
+	Host  string
+}
+
+// ForwardedInfo is the original client request as seen by the first proxy
+// in front of niseroku
+type ForwardedInfo struct {
+	Addr  string
+	Proto string
+	Host  string
+}
+
+// isTrustedProxy returns true if the address is within the niseroku.toml
+// [server] trusted-proxies networks
+func (rp *ReverseProxy) isTrustedProxy(addr string) (trusted bool) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	trusted = rp.config.Server.IsTrustedProxy(addr)
+	return
+}
+
+// acceptProxyProtocol returns true if PROXY protocol headers are accepted
+// from the address, no addresses are accepted if trusted-proxies is not set
+func (rp *ReverseProxy) acceptProxyProtocol(addr string) (accept bool) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	accept = rp.config.Server.IsTrustedProxy(addr)
+	return
+}
+
+// getForwarded returns the client address, scheme and host of the request;
+// the forwarding headers are only used when the connecting peer is a trusted
+// proxy and the client address is the nearest untrusted hop
+func (rp *ReverseProxy) getForwarded(r *http.Request) (info ForwardedInfo) {
+	info.Addr = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		info.Addr = host
+	}
+	info.Proto = CheckAB("https", "http", r.TLS != nil)
+	info.Host = r.Host
+
+	if !rp.isTrustedProxy(info.Addr) {
+		return
+	}
+
+	var hops []ForwardedHop
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		hops = parseForwardedHeader(values)
+	} else if values = r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, value := range strings.Split(strings.Join(values, ","), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				hops = append(hops, ForwardedHop{For: value})
+			}
+		}
+		if len(hops) > 0 {
+			hops[0].Proto = firstHeaderValue(r, "X-Forwarded-Proto")
+			hops[0].Host = firstHeaderValue(r, "X-Forwarded-Host")
+		}
+	}
+	if len(hops) == 0 {
+		return
+	}
+
+	// the nearest hop not within trusted-proxies is the client, or the
+	// furthest hop if all of them are trusted
+	client := hops[0]
+	for idx := len(hops) - 1; idx >= 0; idx-- {
+		if addr := parseForwardedAddr(hops[idx].For); addr != "" && !rp.isTrustedProxy(addr) {
+			client = hops[idx]
+			break
+		}
+	}
+	if addr := parseForwardedAddr(client.For); addr != "" {
+		info.Addr = addr
+	}
+	if proto := strings.ToLower(CheckAB(client.Proto, hops[0].Proto, client.Proto != "")); proto == "http" || proto == "https" {
+		info.Proto = proto
+	}
+	if host := CheckAB(client.Host, hops[0].Host, client.Host != ""); host != "" {
+		info.Host = host
+	}
+	return
+}
+
+// isSecureRequest returns true for https requests, including those received
+// from a trusted proxy which terminated the tls connection
+func (rp *ReverseProxy) isSecureRequest(r *http.Request) (secure bool) {
+	secure = r.TLS != nil || rp.getForwarded(r).Proto == "https"
+	return
+}
+
+// setForwardedHeaders replaces any forwarding headers of the origin request
+// with the client information
+func setForwardedHeaders(req *http.Request, info ForwardedInfo) {
+	req.Header.Set("X-Forwarded-For", info.Addr)
+	req.Header.Set("X-Forwarded-Proto", info.Proto)
+	req.Header.Set("X-Forwarded-Host", info.Host)
+	req.Header.Set("X-Real-IP", info.Addr)
+	forwardedFor := info.Addr
+	if ip := net.ParseIP(info.Addr); ip != nil && ip.To4() == nil {
+		forwardedFor = `"[` + info.Addr + `]"`
+	}
+	host := strings.ReplaceAll(info.Host, `"`, "")
+	req.Header.Set("Forwarded", "for="+forwardedFor+";proto="+info.Proto+";host=\""+host+"\"")
+}
+
+// parseForwardedHeader returns the hops of the RFC 7239 Forwarded header
+func parseForwardedHeader(values []string) (hops []ForwardedHop) {
+	for _, element := range strings.Split(strings.Join(values, ","), ",") {
+		var hop ForwardedHop
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found {
+				continue
+			}
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(key) {
+			case "for":
+				hop.For = value
+			case "proto":
+				hop.Proto = strings.ToLower(value)
+			case "host":
+				hop.Host = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return
+}
+
+// parseForwardedAddr returns the IP address of the forwarded value, without
+// any port or brackets, or an empty string if it is not an IP address
+func parseForwardedAddr(value string) (addr string) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if ip := net.ParseIP(value); ip != nil {
+		addr = ip.String()
+	}
+	return
+}
+
+func firstHeaderValue(r *http.Request, name string) (value string) {
+	if value = r.Header.Get(name); value != "" {
+		value, _, _ = strings.Cut(value, ",")
+		value = strings.TrimSpace(value)
+	}
+	return
+}
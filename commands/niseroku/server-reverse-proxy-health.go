@@ -0,0 +1,261 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-corelibs/maps"
+)
+
+type WorkerHealth struct {
+	App       string
+	Hash      string
+	Port      int
+	Healthy   bool
+	Checking  bool
+	Successes int
+	Failures  int
+	Status    int
+	LastCheck time.Time
+	LastError string
+}
+
+type HealthChecks struct {
+	data map[int]*WorkerHealth
+
+	sync.RWMutex
+}
+
+func NewHealthChecks() (h *HealthChecks) {
+	h = new(HealthChecks)
+	h.data = make(map[int]*WorkerHealth)
+	return
+}
+
+// IsHealthy returns false only if the given port has failed enough health
+// checks to be considered unhealthy
+func (h *HealthChecks) IsHealthy(port int) (healthy bool) {
+	h.RLock()
+	defer h.RUnlock()
+	if wh, ok := h.data[port]; ok {
+		healthy = wh.Healthy
+	} else {
+		healthy = true
+	}
+	return
+}
+
+// Begin returns true if the worker is due to be checked and flags the worker
+// as currently being checked
+func (h *HealthChecks) Begin(app *Application, worker *SlugWorker, interval time.Duration) (due bool) {
+	h.Lock()
+	defer h.Unlock()
+	wh, ok := h.data[worker.Port]
+	if !ok || wh.Hash != worker.Hash {
+		// new worker, or a new worker reusing a port
+		wh = &WorkerHealth{
+			App:     app.Name,
+			Hash:    worker.Hash,
+			Port:    worker.Port,
+			Healthy: true,
+		}
+		h.data[worker.Port] = wh
+	}
+	if due = !wh.Checking && time.Since(wh.LastCheck) >= interval; due {
+		wh.Checking = true
+	}
+	return
+}
+
+// Complete records the health check result, returning true if the healthy
+// state of the worker changed
+func (h *HealthChecks) Complete(port int, status int, err error, check *AppHealthCheck) (wh WorkerHealth, changed bool) {
+	h.Lock()
+	defer h.Unlock()
+	var ok bool
+	var found *WorkerHealth
+	if found, ok = h.data[port]; !ok {
+		return
+	}
+	found.Checking = false
+	found.Status = status
+	found.LastCheck = time.Now()
+	if err == nil {
+		found.LastError = ""
+		found.Failures = 0
+		if found.Successes += 1; !found.Healthy && found.Successes >= check.GetHealthyThreshold() {
+			found.Healthy = true
+			changed = true
+		}
+	} else {
+		found.LastError = err.Error()
+		found.Successes = 0
+		if found.Failures += 1; found.Healthy && found.Failures >= check.GetUnhealthyThreshold() {
+			found.Healthy = false
+			changed = true
+		}
+	}
+	wh = *found
+	return
+}
+
+// Prune removes all entries for ports not present in the active lookup
+func (h *HealthChecks) Prune(active map[int]struct{}) {
+	h.Lock()
+	defer h.Unlock()
+	for port := range h.data {
+		if _, ok := active[port]; !ok {
+			delete(h.data, port)
+		}
+	}
+}
+
+// String returns one tab-separated line per worker: app, hash, port, state,
+// last status code, consecutive failures, last check and last error
+func (h *HealthChecks) String() (summary string) {
+	h.RLock()
+	defer h.RUnlock()
+	for _, port := range maps.SortedNumbers(h.data) {
+		wh := h.data[port]
+		state := "healthy"
+		if !wh.Healthy {
+			state = "unhealthy"
+		}
+		lastCheck := "-"
+		if !wh.LastCheck.IsZero() {
+			lastCheck = wh.LastCheck.Format(time.RFC3339)
+		}
+		lastError := strings.ReplaceAll(wh.LastError, "\t", " ")
+		lastError = strings.ReplaceAll(lastError, "\n", " ")
+		summary += fmt.Sprintf("%s\t%s\t%d\t%s\t%d\t%d\t%s\t%s\n", wh.App, wh.Hash, wh.Port, state, wh.Status, wh.Failures, lastCheck, lastError)
+	}
+	return
+}
+
+func (rp *ReverseProxy) PortIsHealthy(port int) (healthy bool) {
+	healthy = rp.health.IsHealthy(port)
+	return
+}
+
+func (rp *ReverseProxy) healthCheckServe() {
+	rp.RLock()
+	stop := rp.healthStop
+	rp.RUnlock()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rp.healthCheckAll()
+		}
+	}
+}
+
+func (rp *ReverseProxy) healthCheckAll() {
+	rp.config.RLock()
+	var apps []*Application
+	for _, app := range rp.config.Applications {
+		apps = append(apps, app)
+	}
+	rp.config.RUnlock()
+
+	active := make(map[int]struct{})
+	for _, app := range apps {
+		if !app.HealthCheck.Enabled() || app.Maintenance {
+			continue
+		}
+		interval := app.HealthCheck.GetInterval()
+		for _, slug := range rp.getServingSlugs(app) {
+			for _, worker := range slug.GetLiveWorkers() {
+				active[worker.Port] = struct{}{}
+				if rp.health.Begin(app, worker, interval) {
+					go rp.healthCheckWorker(app, slug, worker.Hash, worker.Port)
+				}
+			}
+		}
+	}
+	rp.health.Prune(active)
+}
+
+// getServingSlugs returns the slugs of the app which may receive requests,
+// this slug along with the next slug (a canary) and any mirror shadow slug
+func (rp *ReverseProxy) getServingSlugs(app *Application) (slugs []*Slug) {
+	seen := make(map[string]struct{})
+	add := func(slug *Slug) {
+		if slug == nil {
+			return
+		} else if _, present := seen[slug.Name]; !present {
+			seen[slug.Name] = struct{}{}
+			slugs = append(slugs, slug)
+		}
+	}
+	add(app.GetThisSlug())
+	add(app.GetNextSlug())
+	if app.Mirror.Enabled() {
+		add(app.Mirror.GetShadowSlug(app))
+	}
+	return
+}
+
+func (rp *ReverseProxy) healthCheckWorker(app *Application, slug *Slug, hash string, port int) {
+	check := app.HealthCheck
+	ctx, cancel := context.WithTimeout(context.Background(), check.GetTimeout())
+	defer cancel()
+
+	var err error
+	var status int
+	var req *http.Request
+	target := app.Origin.Scheme + "://" + app.Origin.Host + ":" + strconv.Itoa(port) + check.Path
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, target, nil); err == nil {
+		if hosts := app.GetDomainHosts(); len(hosts) > 0 {
+			req.Host = hosts[0]
+		}
+		req.Header.Set("User-Agent", "niseroku-health-check")
+		req.Header.Set("X-Proxy", "niseroku")
+		var response *http.Response
+		if response, err = slug.HttpClientDo(port, req); err == nil {
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+			if status = response.StatusCode; status < 200 || status >= 400 {
+				err = fmt.Errorf("unexpected status: %d", status)
+			}
+		}
+	}
+
+	if wh, changed := rp.health.Complete(port, status, err, check); changed {
+		state := "healthy"
+		if !wh.Healthy {
+			state = "unhealthy"
+		}
+		rp.metrics.Inc("niseroku_health_check_transitions_total", "app", app.Name, "state", state)
+		if wh.Healthy {
+			rp.LogInfoF("[health] worker recovered: %v [%v] on port %d\n", app.Name, hash, port)
+			app.LogInfoF("health check recovered: %v [%v] on port %d\n", slug.Name, hash, port)
+		} else {
+			rp.LogErrorF("[health] worker unhealthy: %v [%v] on port %d - %v\n", app.Name, hash, port, err)
+			app.LogErrorF("health check failed: %v [%v] on port %d - %v\n", slug.Name, hash, port, err)
+		}
+	}
+}
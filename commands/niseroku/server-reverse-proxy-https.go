@@ -0,0 +1,90 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// httpsRedirectHandler redirects plain http requests to https, except for
+// apps with force-https explicitly disabled and requests a trusted proxy
+// received over https, which are given to next
+func (rp *ReverseProxy) httpsRedirectHandler(next http.Handler) (h http.Handler) {
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		domain, app, ok := rp.GetAppDomain(r)
+		if (ok && !app.Proxy.GetForceHttps()) || rp.isSecureRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if redirect := rp.GetDomainRedirect(domain); redirect != nil {
+			// go straight to the redirect destination
+			http.Redirect(w, r, rp.makeRedirectUrl(r, redirect.ToHost, redirect.GetKeepPath(), true), redirect.GetStatus())
+			return
+		}
+
+		status := http.StatusFound
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// preserve the method and body
+			status = http.StatusTemporaryRedirect
+		}
+		http.Redirect(w, r, rp.makeRedirectUrl(r, r.Host, true, true), status)
+	})
+	return
+}
+
+// makeRedirectUrl returns the URL for the given host, using the configured
+// http or https port and optionally keeping the request path and query
+func (rp *ReverseProxy) makeRedirectUrl(r *http.Request, host string, keepPath, secure bool) (target string) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	scheme, port, defaultPort := "http", rp.config.Ports.Http, 80
+	if secure {
+		scheme, port, defaultPort = "https", rp.config.Ports.Https, 443
+	}
+	if port != defaultPort {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	target = scheme + "://" + host
+	if keepPath {
+		target += r.URL.RequestURI()
+	} else {
+		target += "/"
+	}
+	return
+}
+
+// serveDomainRedirect redirects requests for a redirect-only host, returning
+// the response status
+func (rp *ReverseProxy) serveDomainRedirect(w http.ResponseWriter, r *http.Request, app *Application, redirect *AppRedirect) (status int) {
+	secure := rp.isSecureRequest(r) || (rp.config.EnableSSL && app.Proxy.GetForceHttps())
+	status = redirect.GetStatus()
+	http.Redirect(w, r, rp.makeRedirectUrl(r, redirect.ToHost, redirect.GetKeepPath(), secure), status)
+	return
+}
+
+// applyHstsHeader sets the app's Strict-Transport-Security header on https
+// responses
+func (rp *ReverseProxy) applyHstsHeader(app *Application, w http.ResponseWriter, r *http.Request) {
+	if app == nil || !rp.isSecureRequest(r) {
+		return
+	}
+	if value := app.Proxy.GetHstsHeader(); value != "" {
+		w.Header().Set("Strict-Transport-Security", value)
+	}
+}
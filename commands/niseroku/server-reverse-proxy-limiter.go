@@ -25,27 +25,48 @@ import (
 	"github.com/didip/tollbooth/v7"
 	"github.com/didip/tollbooth/v7/limiter"
 	"github.com/kataras/requestid"
-
-	beNet "github.com/go-enjin/be/pkg/net"
-	"github.com/go-enjin/be/pkg/net/serve"
 )
 
+func newRateLimiter(limits RateLimit) (lmt *limiter.Limiter) {
+	lmt = tollbooth.NewLimiter(
+		limits.Max,
+		&limiter.ExpirableOptions{
+			DefaultExpirationTTL: limits.TTL,
+		},
+	)
+	if limits.Burst > 0 {
+		lmt.SetBurst(limits.Burst)
+	}
+	lmt.SetStatusCode(http.StatusTooManyRequests)
+	lmt.SetMessage("429 - Too Many Requests")
+	lmt.SetMessageContentType("text/plain; charset=utf-8")
+	return
+}
+
+func updateRateLimiter(lmt *limiter.Limiter, limits RateLimit) {
+	lmt.SetMax(limits.Max)
+	if limits.Burst > 0 {
+		lmt.SetBurst(limits.Burst)
+	} else {
+		lmt.SetBurst(int(math.Max(1, limits.Max)))
+	}
+}
+
 func (rp *ReverseProxy) initRateLimiter() {
+	rp.limitersLock.Lock()
+	defer rp.limitersLock.Unlock()
 	if rp.limiter != nil {
 		return
 	}
-	rp.limiter = tollbooth.NewLimiter(
-		rp.config.ProxyLimit.Max,
-		&limiter.ExpirableOptions{
-			DefaultExpirationTTL: rp.config.ProxyLimit.TTL,
-		},
-	)
-	if rp.config.ProxyLimit.Burst > 0 {
-		rp.limiter.SetBurst(rp.config.ProxyLimit.Burst)
+	rp.limiter = newRateLimiter(rp.config.ProxyLimit)
+	rp.appLimiters = make(map[string]*limiter.Limiter)
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	for _, app := range rp.config.Applications {
+		if limits, custom := app.GetProxyLimit(); custom {
+			rp.appLimiters[app.Name] = newRateLimiter(limits)
+		}
 	}
-	rp.limiter.SetStatusCode(http.StatusTooManyRequests)
-	rp.limiter.SetMessage("429 - Too Many Requests")
-	rp.limiter.SetMessageContentType("text/plain; charset=utf-8")
 }
 
 func (rp *ReverseProxy) reloadRateLimiter() {
@@ -53,12 +74,42 @@ func (rp *ReverseProxy) reloadRateLimiter() {
 		rp.initRateLimiter()
 		return
 	}
-	rp.limiter.SetMax(rp.config.ProxyLimit.Max)
-	if rp.config.ProxyLimit.Burst > 0 {
-		rp.limiter.SetBurst(rp.config.ProxyLimit.Burst)
-	} else {
-		rp.limiter.SetBurst(int(math.Max(1, rp.config.ProxyLimit.Max)))
+	rp.limitersLock.Lock()
+	defer rp.limitersLock.Unlock()
+	updateRateLimiter(rp.limiter, rp.config.ProxyLimit)
+	appLimiters := make(map[string]*limiter.Limiter)
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	for _, app := range rp.config.Applications {
+		limits, custom := app.GetProxyLimit()
+		if !custom {
+			continue
+		}
+		if lmt, ok := rp.appLimiters[app.Name]; ok {
+			updateRateLimiter(lmt, limits)
+			appLimiters[app.Name] = lmt
+		} else {
+			appLimiters[app.Name] = newRateLimiter(limits)
+		}
 	}
+	rp.appLimiters = appLimiters
+}
+
+// getRateLimiter returns the app's own limiter and rate limits if the app has
+// a [proxy-limit] table, otherwise the global limiter and rate limits
+func (rp *ReverseProxy) getRateLimiter(app *Application) (lmt *limiter.Limiter, limits RateLimit) {
+	rp.limitersLock.RLock()
+	defer rp.limitersLock.RUnlock()
+	var custom bool
+	if limits, custom = app.GetProxyLimit(); custom {
+		if found, ok := rp.appLimiters[app.Name]; ok {
+			lmt = found
+			return
+		}
+	}
+	lmt = rp.limiter
+	limits = rp.config.ProxyLimit
+	return
 }
 
 func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
@@ -67,24 +118,45 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 
 		var err error
 		var exists bool
+		var slugPort int
 		var thisSlug *Slug
 		var domain, portKey string
 		var app *Application
 
-		remoteAddr := "<nil>"
-		if addr, ee := beNet.GetIpFromRequest(r); ee == nil {
-			remoteAddr = addr
+		w, r = newAccessRecord(w, r)
+
+		remoteAddr := rp.getForwarded(r).Addr
+
+		domain, app, exists = rp.GetAppDomain(r)
+		rp.applyHstsHeader(app, w, r)
+		if rp.denyAccess(w, r, domain, app, remoteAddr) {
+			return
 		}
 
-		if domain, app, exists = rp.GetAppDomain(r); exists {
-			if thisSlug = app.GetThisSlug(); thisSlug != nil {
+		if redirect := rp.GetDomainRedirect(domain); redirect != nil {
+			start := time.Now()
+			status := rp.serveDomainRedirect(w, r, app, redirect)
+			app.LogAccessF(status, remoteAddr, r, start)
+			rp.recordRequest(app, domain, remoteAddr, status, start)
+			return
+		}
+
+		if rp.denyAuth(w, r, app, remoteAddr) {
+			return
+		} else if rp.limitRequestBody(w, r, domain, app, remoteAddr) {
+			return
+		}
+		r = rp.routeCanary(app, r)
+
+		if exists {
+			if thisSlug = rp.getRequestSlug(app, r); thisSlug != nil {
 				_ = thisSlug.Settings.Reload()
 				running, ready := thisSlug.IsRunningReady()
 				if !running || !ready {
 					for i := 0; i < 20; i++ {
 						time.Sleep(100 * time.Millisecond)
 						_, app, _ = rp.GetAppDomain(r)
-						if thisSlug = app.GetThisSlug(); thisSlug != nil {
+						if thisSlug = rp.getRequestSlug(app, r); thisSlug != nil {
 							// rp.LogInfoF("limiter polling [%d] slug running+ready: %v", i, thisSlug.Name)
 							if running, ready = thisSlug.IsRunningReady(); running && ready {
 								break
@@ -94,8 +166,8 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 				}
 				if !running || !ready {
 					err = fmt.Errorf("slug not running or not ready")
-				} else if port := thisSlug.GetLivePort(); port > 0 {
-					portKey = "port|" + strconv.Itoa(port)
+				} else if slugPort = thisSlug.ConsumeLivePort(rp); slugPort > 0 {
+					portKey = "port|" + strconv.Itoa(slugPort)
 					err = nil
 				} else {
 					err = fmt.Errorf("slug has no live ports")
@@ -108,15 +180,19 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 		}
 		if err != nil {
 			rp.LogErrorF("proxy error: %v %v (%v) - %v\n", r.Host, r.URL.String(), remoteAddr, err)
-			serve.Serve404(w, r)
+			rp.serveError(w, r, app, http.StatusNotFound)
 			return
 		}
 
 		var status int
+		var mirror *MirrorRequest
 		if app != nil {
 			start := time.Now()
 			defer func() {
 				app.LogAccessF(status, remoteAddr, r, start)
+				rp.recordRequest(app, domain, remoteAddr, status, start)
+				rp.recordCanary(app, r, status)
+				mirror.Done(status)
 			}()
 		}
 
@@ -127,9 +203,9 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 		go rp.tracking.Increment(trackingKeys...)
 		defer rp.deferDecTracking(trackingKeys...)
 
-		if tbe := tollbooth.LimitByKeys(rp.limiter, []string{domain, remoteAddr}); tbe != nil {
+		lmt, rateLimits := rp.getRateLimiter(app)
+		if tbe := tollbooth.LimitByKeys(lmt, []string{domain, remoteAddr}); tbe != nil {
 			reqId := requestid.Get(r)
-			rateLimits := rp.config.ProxyLimit
 			var delayCount int
 			itrDelay := time.Duration(rateLimits.MaxDelay.Nanoseconds() / int64(rateLimits.DelayScale))
 			totalDelay := time.Duration(0)
@@ -139,10 +215,13 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 			}
 			go rp.tracking.Increment(delayTrackingKeys...)
 			defer rp.deferDecTracking(delayTrackingKeys...)
+			rp.metrics.Inc("niseroku_rate_limit_delayed_total", "app", app.Name)
+			rp.stats.Delayed(trackingKeys[:4]...)
 			for delayCount = 1; delayCount <= rateLimits.DelayScale; delayCount++ {
 				time.Sleep(itrDelay)
 				totalDelay = time.Duration(itrDelay.Nanoseconds() * int64(delayCount))
-				if !rp.limiter.LimitReached(domain) && !rp.limiter.LimitReached(remoteAddr) {
+				getAccessRecord(r).SetDelay(totalDelay)
+				if !lmt.LimitReached(domain) && !lmt.LimitReached(remoteAddr) {
 					if delayCount > 1 && rateLimits.LogAllowed {
 						reqUrl, _, _, _ := DecomposeUrl(r)
 						rp.LogInfoF("[rate] allowed - %v - %v - %v - %v", reqId, remoteAddr, reqUrl, totalDelay)
@@ -154,12 +233,16 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 					rp.LogInfoF("[rate] delayed - %v - %v - %v - %v", reqId, remoteAddr, reqUrl, totalDelay)
 				}
 			}
+			rp.metrics.Observe("niseroku_rate_limit_delay_seconds", MetricsDelayBuckets, totalDelay.Seconds(), "app", app.Name)
 			if delayCount > rateLimits.DelayScale {
-				rp.limiter.ExecOnLimitReached(w, r)
-				if rp.limiter.GetOverrideDefaultResponseWriter() {
+				rp.metrics.Inc("niseroku_rate_limit_rejected_total", "app", app.Name)
+				rp.stats.Limited(trackingKeys[:4]...)
+				status = tbe.StatusCode
+				lmt.ExecOnLimitReached(w, r)
+				if lmt.GetOverrideDefaultResponseWriter() {
 					return
 				}
-				w.Header().Add("Content-Type", rp.limiter.GetMessageContentType())
+				w.Header().Add("Content-Type", lmt.GetMessageContentType())
 				w.WriteHeader(tbe.StatusCode)
 				_, _ = w.Write([]byte(tbe.Message))
 				if rateLimits.LogLimited {
@@ -171,24 +254,36 @@ func (rp *ReverseProxy) ProxyHttpHandler() (h http.Handler) {
 		}
 
 		// request is allowed
-		if status, err = rp.ServeOriginHTTP(app, remoteAddr, w, r); err != nil {
+		mirror = rp.startMirror(app, remoteAddr, r)
+		if status, err = rp.ServeCachedHTTP(app, slugPort, remoteAddr, w, r); err != nil {
+			if isTooLargeError(err) {
+				status = http.StatusRequestEntityTooLarge
+				rp.serveTooLarge(w, r, domain, app, remoteAddr)
+				return
+			}
 			if strings.Contains(err.Error(), "context canceled") {
 				status = http.StatusTeapot
 				err = nil
 				return
 			}
-			if strings.Contains(err.Error(), "connection refused") {
+			if isOriginDialError(err) {
+				// the worker is not listening, including missing unix sockets
 				status = http.StatusBadGateway
-				serve.Serve502(w, r)
+				rp.serveError(w, r, app, http.StatusBadGateway)
 				return
 			}
-			rp.LogErrorF("origin request error: %v - %v - %#+v\n", app.Name, err, r)
+			rp.LogErrorF("origin request error: %v - %v -- %v %v\n", app.Name, err, r.Method, r.URL.String())
 			status = http.StatusInternalServerError
-			serve.Serve500(w, r)
+			rp.serveError(w, r, app, http.StatusInternalServerError)
 		}
 	}))
 }
 
+func (rp *ReverseProxy) PortInFlight(port int) (inFlight int64) {
+	inFlight = rp.tracking.Get("port|" + strconv.Itoa(port))
+	return
+}
+
 func (rp *ReverseProxy) deferDecTracking(keys ...string) {
 	go func() {
 		// time.Sleep(DefaultProxyLimitsStatLifetime)
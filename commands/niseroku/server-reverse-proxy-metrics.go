@@ -0,0 +1,113 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// metricsText updates the in-flight gauges and returns all metrics in the
+// Prometheus text exposition format
+func (rp *ReverseProxy) metricsText() (text string) {
+	rp.metrics.ResetGauge("niseroku_http_requests_in_flight")
+	for name, value := range rp.tracking.Prefixed("app|") {
+		rp.metrics.Set("niseroku_http_requests_in_flight", float64(value), "app", name)
+	}
+	text = rp.metrics.String()
+	return
+}
+
+// recordRequest updates the request counters and latency histogram
+func (rp *ReverseProxy) recordRequest(app *Application, domain, remoteAddr string, status int, start time.Time) {
+	latency := time.Since(start)
+	rp.metrics.Inc("niseroku_http_requests_total", "app", app.Name, "host", domain, "status", strconv.Itoa(status))
+	rp.metrics.Observe("niseroku_http_request_duration_seconds", MetricsLatencyBuckets, latency.Seconds(), "app", app.Name)
+	rp.stats.Request(status, latency, "__total__", "app|"+app.Name, "host|"+domain, "addr|"+remoteAddr)
+}
+
+// recordMetricsEvent records events reported by other niseroku processes with
+// the metrics-event control command
+func (rp *ReverseProxy) recordMetricsEvent(argv []string) (err error) {
+	if len(argv) == 0 {
+		err = fmt.Errorf("metrics-event requires an event name")
+		return
+	}
+	switch argv[0] {
+	case "restart":
+		if len(argv) != 2 {
+			err = fmt.Errorf("metrics-event restart requires an app name")
+			return
+		}
+		rp.metrics.Inc("niseroku_slug_restarts_total", "app", argv[1])
+	case "deploy":
+		if len(argv) != 3 {
+			err = fmt.Errorf("metrics-event deploy requires an app name and outcome")
+			return
+		}
+		rp.metrics.Inc("niseroku_deploys_total", "app", argv[1], "outcome", argv[2])
+	default:
+		err = fmt.Errorf("unknown metrics-event: %v", argv[0])
+	}
+	return
+}
+
+func (rp *ReverseProxy) metricsHttpHandler() (h http.Handler) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(rp.metricsText()))
+		}
+	})
+	h = mux
+	return
+}
+
+func (rp *ReverseProxy) bindMetrics() (err error) {
+	if rp.config.Metrics.Listen == "" {
+		return
+	}
+	rp.metricsHttp = &http.Server{
+		Addr:              rp.config.Metrics.Listen,
+		Handler:           rp.metricsHttpHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if rp.metricsListener, err = net.Listen("tcp", rp.config.Metrics.Listen); err != nil {
+		err = fmt.Errorf("error listening for metrics: %v", err)
+	}
+	return
+}
+
+func (rp *ReverseProxy) metricsServe() (err error) {
+	if rp.metricsHttp != nil && rp.metricsListener != nil {
+		if err = rp.metricsHttp.Serve(rp.metricsListener); errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("error serving metrics: %v", err)
+		}
+	}
+	return
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type mirrorOutcome struct {
+	status  int
+	latency time.Duration
+}
+
+// MirrorRequest pairs the response a client received with the response of
+// the shadow slug to a copy of the same request
+type MirrorRequest struct {
+	started time.Time
+	primary chan mirrorOutcome
+}
+
+// Done records the response status the client received, it is safe to call
+// on a nil MirrorRequest
+func (m *MirrorRequest) Done(status int) {
+	if m == nil {
+		return
+	}
+	select {
+	case m.primary <- mirrorOutcome{status: status, latency: time.Since(m.started)}:
+	default:
+	}
+}
+
+// MirrorState is the summary of one app's mirrored requests
+type MirrorState struct {
+	App  string
+	Slug string
+	Rate float64
+
+	Mirrored         int64
+	Dropped          int64
+	Skipped          int64
+	Errors           int64
+	Compared         int64
+	StatusMatches    int64
+	StatusMismatches int64
+	PrimaryErrors    int64
+	ShadowErrors     int64
+	PrimaryLatency   time.Duration
+	ShadowLatency    time.Duration
+	Mismatches       []ProxyControlMirrorMismatch
+
+	slots chan struct{}
+
+	sync.RWMutex
+}
+
+func newMirrorState(app *Application, shadow *Slug) (s *MirrorState) {
+	s = &MirrorState{
+		App:   app.Name,
+		Slug:  shadow.Name,
+		Rate:  app.Mirror.Rate,
+		slots: make(chan struct{}, app.Mirror.GetMaxConcurrent()),
+	}
+	return
+}
+
+func (s *MirrorState) count(counter *int64) {
+	s.Lock()
+	defer s.Unlock()
+	*counter += 1
+}
+
+// Record compares the primary and shadow responses of one mirrored request
+func (s *MirrorState) Record(method, path string, primary, shadow mirrorOutcome) {
+	s.Lock()
+	defer s.Unlock()
+	s.Compared += 1
+	s.PrimaryLatency += primary.latency
+	s.ShadowLatency += shadow.latency
+	if primary.status >= 500 {
+		s.PrimaryErrors += 1
+	}
+	if shadow.status >= 500 {
+		s.ShadowErrors += 1
+	}
+	if primary.status == shadow.status {
+		s.StatusMatches += 1
+		return
+	}
+	s.StatusMismatches += 1
+	s.Mismatches = append(s.Mismatches, ProxyControlMirrorMismatch{
+		Time:    time.Now(),
+		Method:  method,
+		Path:    path,
+		Primary: primary.status,
+		Shadow:  shadow.status,
+	})
+	if extra := len(s.Mismatches) - DefaultMirrorMaxMismatches; extra > 0 {
+		s.Mismatches = s.Mismatches[extra:]
+	}
+}
+
+func (s *MirrorState) Info() (info ProxyControlMirror) {
+	s.RLock()
+	defer s.RUnlock()
+	info = ProxyControlMirror{
+		App:              s.App,
+		Slug:             s.Slug,
+		Rate:             s.Rate,
+		InFlight:         len(s.slots),
+		Mirrored:         s.Mirrored,
+		Dropped:          s.Dropped,
+		Skipped:          s.Skipped,
+		Errors:           s.Errors,
+		Compared:         s.Compared,
+		StatusMatches:    s.StatusMatches,
+		StatusMismatches: s.StatusMismatches,
+		PrimaryErrors:    s.PrimaryErrors,
+		ShadowErrors:     s.ShadowErrors,
+		Mismatches:       append([]ProxyControlMirrorMismatch{}, s.Mismatches...),
+	}
+	if s.Compared > 0 {
+		info.PrimaryLatency = (s.PrimaryLatency / time.Duration(s.Compared)).Round(time.Microsecond).String()
+		info.ShadowLatency = (s.ShadowLatency / time.Duration(s.Compared)).Round(time.Microsecond).String()
+	}
+	return
+}
+
+type Mirrors struct {
+	data map[string]*MirrorState
+
+	sync.RWMutex
+}
+
+func NewMirrors() (m *Mirrors) {
+	m = new(Mirrors)
+	m.data = make(map[string]*MirrorState)
+	return
+}
+
+// Get returns the mirror state of the app, starting over if the shadow slug
+// or the mirror settings changed
+func (m *Mirrors) Get(app *Application, shadow *Slug) (state *MirrorState) {
+	m.RLock()
+	state, ok := m.data[app.Name]
+	m.RUnlock()
+	if ok && state.Slug == shadow.Name && state.Rate == app.Mirror.Rate && cap(state.slots) == app.Mirror.GetMaxConcurrent() {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	if state, ok = m.data[app.Name]; !ok || state.Slug != shadow.Name || state.Rate != app.Mirror.Rate || cap(state.slots) != app.Mirror.GetMaxConcurrent() {
+		state = newMirrorState(app, shadow)
+		m.data[app.Name] = state
+	}
+	return
+}
+
+// Prune removes the mirror state of apps which no longer mirror requests
+func (m *Mirrors) Prune(apps map[string]*Application) {
+	m.Lock()
+	defer m.Unlock()
+	for name := range m.data {
+		if app, ok := apps[name]; !ok || !app.Mirror.Enabled() {
+			delete(m.data, name)
+		}
+	}
+}
+
+// List returns the mirror states of the named app, or of all apps if name is
+// empty
+func (m *Mirrors) List(name string) (list []ProxyControlMirror) {
+	m.RLock()
+	defer m.RUnlock()
+	list = []ProxyControlMirror{}
+	for appName, state := range m.data {
+		if name == "" || name == appName {
+			list = append(list, state.Info())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].App < list[j].App
+	})
+	return
+}
+
+func (rp *ReverseProxy) reloadMirrors() {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	rp.mirrors.Prune(rp.config.Applications)
+}
+
+// bufferMirrorBody reads the request body into memory so that it can be sent
+// to both slugs, returning false with the body still readable by the primary
+// request if it is larger than the limit or could not be read
+func bufferMirrorBody(r *http.Request, limit int64) (body []byte, ok bool) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		ok = true
+		return
+	} else if r.ContentLength > limit {
+		return
+	}
+	original := r.Body
+	var err error
+	body, err = io.ReadAll(io.LimitReader(original, limit+1))
+	if ok = err == nil && int64(len(body)) <= limit; ok {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{bytes.NewReader(body), original}
+		return
+	}
+	// replay what was read, followed by the rest of the body or the read error
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), original), original}
+	body = nil
+	return
+}
+
+// startMirror copies a sample of the app's requests to the shadow slug, the
+// returned MirrorRequest is nil if the request is not mirrored
+func (rp *ReverseProxy) startMirror(app *Application, forwardFor string, r *http.Request) (mirror *MirrorRequest) {
+	if !app.Mirror.Samples(r) {
+		return
+	}
+	shadow := app.Mirror.GetShadowSlug(app)
+	if shadow == nil {
+		return
+	} else if serving := rp.getRequestSlug(app, r); serving != nil && serving.Name == shadow.Name {
+		// the shadow slug is serving this request, ie: as the canary
+		return
+	}
+	state := rp.mirrors.Get(app, shadow)
+
+	ports := shadow.GetLivePorts()
+	if len(ports) == 0 {
+		state.count(&state.Dropped)
+		rp.metrics.Inc("niseroku_mirror_requests_total", "app", app.Name, "result", "dropped")
+		return
+	}
+
+	body, ok := bufferMirrorBody(r, app.Mirror.GetMaxBodySize())
+	if !ok {
+		state.count(&state.Skipped)
+		rp.metrics.Inc("niseroku_mirror_requests_total", "app", app.Name, "result", "skipped")
+		return
+	}
+
+	select {
+	case state.slots <- struct{}{}:
+	default:
+		state.count(&state.Dropped)
+		rp.metrics.Inc("niseroku_mirror_requests_total", "app", app.Name, "result", "dropped")
+		return
+	}
+
+	timeout := shadow.GetOriginRequestTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	req := rp.prepareOriginRequest(ctx, app, forwardFor, r)
+	req.Header.Set("X-Niseroku-Mirror", "true")
+	req.Body = http.NoBody
+	req.GetBody = nil
+	req.ContentLength = int64(len(body))
+	req.TransferEncoding = nil
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	method, path := r.Method, r.URL.Path
+	port := ports[rand.Intn(len(ports))]
+
+	mirror = &MirrorRequest{started: time.Now(), primary: make(chan mirrorOutcome, 1)}
+	state.count(&state.Mirrored)
+
+	go func() {
+		defer cancel()
+
+		var shadowOutcome mirrorOutcome
+		response, err := shadow.HttpClientDo(port, req)
+		if err == nil {
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+			shadowOutcome.status = response.StatusCode
+		}
+		shadowOutcome.latency = time.Since(mirror.started)
+		<-state.slots
+
+		if err != nil {
+			state.count(&state.Errors)
+			rp.metrics.Inc("niseroku_mirror_requests_total", "app", app.Name, "result", "error")
+			rp.LogErrorF("[mirror] %v request error: %v %v - %v\n", app.Name, method, path, err)
+			return
+		}
+
+		select {
+		case primaryOutcome := <-mirror.primary:
+			state.Record(method, path, primaryOutcome, shadowOutcome)
+			rp.metrics.Inc("niseroku_mirror_requests_total", "app", app.Name, "result", "compared")
+		case <-time.After(timeout):
+			// the client response is taking too long to compare against
+		}
+	}()
+	return
+}
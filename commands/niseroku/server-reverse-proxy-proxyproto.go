@@ -0,0 +1,250 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// proxyProtoV1MaxLength is the longest valid v1 header, including CRLF
+	proxyProtoV1MaxLength = 107
+	// proxyProtoHeaderTimeout limits how long a client may take to send the
+	// PROXY protocol header
+	proxyProtoHeaderTimeout = 10 * time.Second
+)
+
+var proxyProtoV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtoListener accepts connections which may start with a PROXY
+// protocol v1 or v2 header, the header is read on the first use of the
+// connection so that slow clients do not block Accept
+type proxyProtoListener struct {
+	net.Listener
+	trusted func(addr string) bool
+}
+
+func newProxyProtoListener(listener net.Listener, trusted func(addr string) bool) (l *proxyProtoListener) {
+	l = &proxyProtoListener{Listener: listener, trusted: trusted}
+	return
+}
+
+func (l *proxyProtoListener) Accept() (conn net.Conn, err error) {
+	if conn, err = l.Listener.Accept(); err != nil {
+		return
+	}
+	conn = &proxyProtoConn{Conn: conn, reader: bufio.NewReader(conn), trusted: l.trusted}
+	return
+}
+
+type proxyProtoConn struct {
+	net.Conn
+	reader  *bufio.Reader
+	trusted func(addr string) bool
+
+	once       sync.Once
+	remoteAddr net.Addr
+	localAddr  net.Addr
+	err        error
+
+	deadline     time.Time
+	deadlineLock sync.Mutex
+}
+
+func (c *proxyProtoConn) init() {
+	c.once.Do(func() {
+		c.remoteAddr = c.Conn.RemoteAddr()
+		c.localAddr = c.Conn.LocalAddr()
+		if host, _, ee := net.SplitHostPort(c.remoteAddr.String()); ee != nil || !c.trusted(host) {
+			// untrusted peers are used as-is, any header they send is not
+			// valid http and is rejected by the server
+			return
+		}
+		// the header read is limited by the earlier of the header timeout and
+		// the server's read deadline, which is restored afterwards
+		c.deadlineLock.Lock()
+		deadline := c.deadline
+		c.deadlineLock.Unlock()
+		headerDeadline := time.Now().Add(proxyProtoHeaderTimeout)
+		if !deadline.IsZero() && deadline.Before(headerDeadline) {
+			headerDeadline = deadline
+		}
+		_ = c.Conn.SetReadDeadline(headerDeadline)
+		defer func() {
+			c.deadlineLock.Lock()
+			defer c.deadlineLock.Unlock()
+			_ = c.Conn.SetReadDeadline(c.deadline)
+		}()
+		var src, dst net.Addr
+		if src, dst, c.err = readProxyProtoHeader(c.reader); c.err == nil && src != nil {
+			c.remoteAddr, c.localAddr = src, dst
+		}
+	})
+}
+
+func (c *proxyProtoConn) Read(p []byte) (n int, err error) {
+	if c.init(); c.err != nil {
+		err = c.err
+		return
+	}
+	n, err = c.reader.Read(p)
+	return
+}
+
+// SetDeadline records the read deadline for restoring after the header read
+func (c *proxyProtoConn) SetDeadline(t time.Time) (err error) {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+	c.deadline = t
+	err = c.Conn.SetDeadline(t)
+	return
+}
+
+// SetReadDeadline records the read deadline for restoring after the header
+// read
+func (c *proxyProtoConn) SetReadDeadline(t time.Time) (err error) {
+	c.deadlineLock.Lock()
+	defer c.deadlineLock.Unlock()
+	c.deadline = t
+	err = c.Conn.SetReadDeadline(t)
+	return
+}
+
+func (c *proxyProtoConn) RemoteAddr() (addr net.Addr) {
+	c.init()
+	addr = c.remoteAddr
+	return
+}
+
+func (c *proxyProtoConn) LocalAddr() (addr net.Addr) {
+	c.init()
+	addr = c.localAddr
+	return
+}
+
+// readProxyProtoHeader consumes a v1 or v2 header if present, src and dst are
+// nil for connections without a header and for LOCAL or UNKNOWN headers
+func readProxyProtoHeader(reader *bufio.Reader) (src, dst net.Addr, err error) {
+	var peek []byte
+	if peek, err = reader.Peek(len(proxyProtoV2Signature)); err != nil {
+		// too short for a header, left for the server to handle
+		err = nil
+		return
+	}
+	switch {
+	case bytes.Equal(peek, proxyProtoV2Signature):
+		src, dst, err = readProxyProtoV2(reader)
+	case bytes.HasPrefix(peek, []byte("PROXY ")):
+		src, dst, err = readProxyProtoV1(reader)
+	}
+	return
+}
+
+func readProxyProtoV1(reader *bufio.Reader) (src, dst net.Addr, err error) {
+	var line []byte
+	for len(line) < proxyProtoV1MaxLength {
+		var b byte
+		if b, err = reader.ReadByte(); err != nil {
+			err = fmt.Errorf("error reading proxy protocol v1 header: %v", err)
+			return
+		}
+		if line = append(line, b); b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		err = fmt.Errorf("invalid proxy protocol v1 header")
+		return
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return
+	} else if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		err = fmt.Errorf("invalid proxy protocol v1 header: %q", string(line))
+		return
+	}
+	srcIp, dstIp := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, srcErr := strconv.ParseUint(fields[4], 10, 16)
+	dstPort, dstErr := strconv.ParseUint(fields[5], 10, 16)
+	if srcIp == nil || dstIp == nil || srcErr != nil || dstErr != nil {
+		err = fmt.Errorf("invalid proxy protocol v1 addresses: %q", string(line))
+		return
+	}
+	src = &net.TCPAddr{IP: srcIp, Port: int(srcPort)}
+	dst = &net.TCPAddr{IP: dstIp, Port: int(dstPort)}
+	return
+}
+
+func readProxyProtoV2(reader *bufio.Reader) (src, dst net.Addr, err error) {
+	header := make([]byte, 16)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		err = fmt.Errorf("error reading proxy protocol v2 header: %v", err)
+		return
+	}
+	if version := header[12] >> 4; version != 2 {
+		err = fmt.Errorf("invalid proxy protocol v2 version: %d", version)
+		return
+	}
+	command, family := header[12]&0x0f, header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err = io.ReadFull(reader, payload); err != nil {
+		err = fmt.Errorf("error reading proxy protocol v2 addresses: %v", err)
+		return
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL, ie: load balancer health checks
+		return
+	case 0x1:
+	default:
+		err = fmt.Errorf("invalid proxy protocol v2 command: %d", command)
+		return
+	}
+
+	var size int
+	switch family {
+	case 0x11: // TCP over IPv4
+		size = net.IPv4len
+	case 0x21: // TCP over IPv6
+		size = net.IPv6len
+	default:
+		// other families carry no usable tcp addresses
+		return
+	}
+	if len(payload) < size*2+4 {
+		err = fmt.Errorf("invalid proxy protocol v2 address length: %d", len(payload))
+		return
+	}
+	src = &net.TCPAddr{
+		IP:   net.IP(payload[:size]),
+		Port: int(binary.BigEndian.Uint16(payload[size*2:])),
+	}
+	dst = &net.TCPAddr{
+		IP:   net.IP(payload[size : size*2]),
+		Port: int(binary.BigEndian.Uint16(payload[size*2+2:])),
+	}
+	return
+}
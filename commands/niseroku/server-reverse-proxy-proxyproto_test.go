@@ -0,0 +1,134 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func proxyProtoV2Header(command, family byte, payload []byte) (header []byte) {
+	header = append(header, proxyProtoV2Signature...)
+	header = append(header, 0x20|command, family, byte(len(payload)>>8), byte(len(payload)))
+	header = append(header, payload...)
+	return
+}
+
+func TestReadProxyProtoV1(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		src    string
+		dst    string
+		err    bool
+		remain string
+	}{
+		{"tcp4", "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nGET /", "192.0.2.1:56324", "198.51.100.1:443", false, "GET /"},
+		{"tcp6", "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n", "[2001:db8::1]:56324", "[2001:db8::2]:443", false, ""},
+		{"unknown", "PROXY UNKNOWN\r\nGET /", "", "", false, "GET /"},
+		{"unknown addresses", "PROXY UNKNOWN 192.0.2.1 198.51.100.1 1 2\r\n", "", "", false, ""},
+		{"truncated", "PROXY TCP4 192.0.2.1", "", "", true, ""},
+		{"missing cr", "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n", "", "", true, ""},
+		{"too long", "PROXY TCP4 " + strings.Repeat("1", proxyProtoV1MaxLength) + "\r\n", "", "", true, ""},
+		{"unknown protocol", "PROXY UDP4 192.0.2.1 198.51.100.1 56324 443\r\n", "", "", true, ""},
+		{"missing port", "PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n", "", "", true, ""},
+		{"invalid address", "PROXY TCP4 192.0.2.300 198.51.100.1 56324 443\r\n", "", "", true, ""},
+		{"invalid port", "PROXY TCP4 192.0.2.1 198.51.100.1 65536 443\r\n", "", "", true, ""},
+		{"negative port", "PROXY TCP4 192.0.2.1 198.51.100.1 -1 443\r\n", "", "", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(test.input))
+			src, dst, err := readProxyProtoHeader(reader)
+			checkProxyProtoResult(t, reader, src, dst, err, test.src, test.dst, test.err, test.remain)
+		})
+	}
+}
+
+func TestReadProxyProtoV2(t *testing.T) {
+	ipv4 := []byte{192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb}
+	ipv6 := append(append(append([]byte{}, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1),
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2), 0xdc, 0x04, 0x01, 0xbb)
+	withTlvs := append(append([]byte{}, ipv4...), 0x04, 0x00, 0x01, 0x00)
+	badVersion := proxyProtoV2Header(0x1, 0x11, ipv4)
+	badVersion[12] = 0x11
+
+	tests := []struct {
+		name   string
+		input  []byte
+		src    string
+		dst    string
+		err    bool
+		remain string
+	}{
+		{"tcp4", append(proxyProtoV2Header(0x1, 0x11, ipv4), "GET /"...), "192.0.2.1:56324", "198.51.100.1:443", false, "GET /"},
+		{"tcp6", proxyProtoV2Header(0x1, 0x21, ipv6), "[2001:db8::1]:56324", "[2001:db8::2]:443", false, ""},
+		{"tlvs", append(proxyProtoV2Header(0x1, 0x11, withTlvs), "GET /"...), "192.0.2.1:56324", "198.51.100.1:443", false, "GET /"},
+		{"local", append(proxyProtoV2Header(0x0, 0x00, nil), "GET /"...), "", "", false, "GET /"},
+		{"local with addresses", append(proxyProtoV2Header(0x0, 0x11, ipv4), "GET /"...), "", "", false, "GET /"},
+		{"unix family", append(proxyProtoV2Header(0x1, 0x31, make([]byte, 216)), "GET /"...), "", "", false, "GET /"},
+		{"truncated header", proxyProtoV2Header(0x1, 0x11, nil)[:14], "", "", true, ""},
+		{"truncated addresses", proxyProtoV2Header(0x1, 0x11, ipv4)[:20], "", "", true, ""},
+		{"short addresses", proxyProtoV2Header(0x1, 0x11, ipv4[:8]), "", "", true, ""},
+		{"short ipv6 addresses", proxyProtoV2Header(0x1, 0x21, ipv4), "", "", true, ""},
+		{"invalid version", badVersion, "", "", true, ""},
+		{"invalid command", proxyProtoV2Header(0x2, 0x11, ipv4), "", "", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewReader(test.input))
+			src, dst, err := readProxyProtoHeader(reader)
+			checkProxyProtoResult(t, reader, src, dst, err, test.src, test.dst, test.err, test.remain)
+		})
+	}
+}
+
+func TestReadProxyProtoNone(t *testing.T) {
+	for _, input := range []string{"", "GET", "GET / HTTP/1.1\r\n", "PROXY"} {
+		reader := bufio.NewReader(strings.NewReader(input))
+		src, dst, err := readProxyProtoHeader(reader)
+		checkProxyProtoResult(t, reader, src, dst, err, "", "", false, input)
+	}
+}
+
+func checkProxyProtoResult(t *testing.T, reader *bufio.Reader, src, dst net.Addr, err error, expectSrc, expectDst string, expectErr bool, remain string) {
+	t.Helper()
+	if expectErr {
+		if err == nil {
+			t.Errorf("expected an error, got src=%v dst=%v", src, dst)
+		}
+		return
+	} else if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var gotSrc, gotDst string
+	if src != nil {
+		gotSrc = src.String()
+	}
+	if dst != nil {
+		gotDst = dst.String()
+	}
+	if gotSrc != expectSrc || gotDst != expectDst {
+		t.Errorf("got src=%q dst=%q, expected src=%q dst=%q", gotSrc, gotDst, expectSrc, expectDst)
+	}
+	var buffer bytes.Buffer
+	_, _ = buffer.ReadFrom(reader)
+	if buffer.String() != remain {
+		t.Errorf("remaining input %q, expected %q", buffer.String(), remain)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WorkerBreaker struct {
+	Hash     string
+	Failures int
+	OpenedAt time.Time
+	Cooldown time.Duration
+}
+
+// IsOpen returns true if the worker is not to be given new requests, once the
+// cooldown has elapsed the breaker is half-open and the next failure opens it
+// again while the next success closes it
+func (b *WorkerBreaker) IsOpen() (open bool) {
+	open = !b.OpenedAt.IsZero() && time.Since(b.OpenedAt) < b.Cooldown
+	return
+}
+
+// CircuitBreakers tracks consecutive origin request failures per worker port,
+// the worker hash is kept so that a new worker reusing the port starts closed
+type CircuitBreakers struct {
+	data map[int]*WorkerBreaker
+
+	sync.RWMutex
+}
+
+func NewCircuitBreakers() (cb *CircuitBreakers) {
+	cb = new(CircuitBreakers)
+	cb.data = make(map[int]*WorkerBreaker)
+	return
+}
+
+func (cb *CircuitBreakers) IsOpen(port int) (open bool) {
+	cb.RLock()
+	defer cb.RUnlock()
+	if b, ok := cb.data[port]; ok {
+		open = b.IsOpen()
+	}
+	return
+}
+
+// Success closes the breaker of the given port
+func (cb *CircuitBreakers) Success(port int) {
+	cb.Lock()
+	defer cb.Unlock()
+	delete(cb.data, port)
+}
+
+// Failure records a failed origin request, opening the breaker once the
+// threshold is reached; opened is true only if the breaker was closed before
+// this failure
+func (cb *CircuitBreakers) Failure(port int, hash string, threshold int, cooldown time.Duration) (opened bool) {
+	cb.Lock()
+	defer cb.Unlock()
+	b, ok := cb.data[port]
+	if !ok || b.Hash != hash {
+		b = &WorkerBreaker{Hash: hash}
+		cb.data[port] = b
+	}
+	b.Failures += 1
+	if b.Failures >= threshold {
+		opened = b.OpenedAt.IsZero()
+		b.OpenedAt = time.Now()
+		b.Cooldown = cooldown
+	}
+	return
+}
+
+// Prune removes all breakers of ports no longer used by the same worker
+func (cb *CircuitBreakers) Prune(live map[int]string) {
+	cb.Lock()
+	defer cb.Unlock()
+	for port, b := range cb.data {
+		if current, ok := live[port]; !ok || current != b.Hash {
+			delete(cb.data, port)
+		}
+	}
+}
+
+// RetryBudgets limits the retries of each app to a ratio of its requests,
+// every request deposits the app's budget ratio and every retry withdraws one;
+// each app budget starts with, and holds at most, the app's minimum retries
+type RetryBudgets struct {
+	data map[string]float64
+
+	sync.Mutex
+}
+
+func NewRetryBudgets() (rb *RetryBudgets) {
+	rb = new(RetryBudgets)
+	rb.data = make(map[string]float64)
+	return
+}
+
+func (rb *RetryBudgets) Deposit(app string, ratio, minimum float64) {
+	rb.Lock()
+	defer rb.Unlock()
+	tokens, ok := rb.data[app]
+	if !ok {
+		tokens = minimum
+	}
+	if tokens += ratio; tokens > minimum {
+		tokens = minimum
+	}
+	rb.data[app] = tokens
+}
+
+func (rb *RetryBudgets) Withdraw(app string, minimum float64) (allowed bool) {
+	rb.Lock()
+	defer rb.Unlock()
+	tokens, ok := rb.data[app]
+	if !ok {
+		tokens = minimum
+	}
+	if allowed = tokens >= 1; allowed {
+		rb.data[app] = tokens - 1
+	}
+	return
+}
+
+func (rp *ReverseProxy) PortCircuitOpen(port int) (open bool) {
+	open = rp.breakers.IsOpen(port)
+	return
+}
+
+// recordOriginResult updates the circuit breaker of the worker on the given
+// port; client cancellations, too large bodies and errors reading the client
+// body are not failures of the worker, refused connections count towards the
+// breaker-failures threshold like any other failure so that a worker which is
+// briefly not listening, ie: while restarting, is not stopped at once
+func (rp *ReverseProxy) recordOriginResult(app *Application, slug *Slug, port int, req *http.Request, err error) {
+	if err == nil {
+		rp.breakers.Success(port)
+		return
+	} else if errors.Is(err, context.Canceled) || isTooLargeError(err) || isClientBodyError(req) {
+		return
+	}
+	var hash string
+	for _, worker := range slug.GetLiveWorkers() {
+		if worker.Port == port {
+			hash = worker.Hash
+			break
+		}
+	}
+	cooldown := app.Retry.GetBreakerCooldown()
+	if rp.breakers.Failure(port, hash, app.Retry.GetBreakerFailures(), cooldown) {
+		rp.metrics.Inc("niseroku_circuit_breaker_open_total", "app", app.Name)
+		rp.LogErrorF("[retry] worker circuit open for %v: %v [%v] on port %d - %v\n", cooldown, app.Name, hash, port, err)
+		app.LogErrorF("worker circuit open for %v: %v [%v] on port %d - %v\n", cooldown, slug.Name, hash, port, err)
+	}
+}
+
+// nextRetryPort returns a live port not yet tried, or zero if there are none
+func (rp *ReverseProxy) nextRetryPort(slug *Slug, tried map[int]struct{}) (port int) {
+	for range slug.GetLivePorts() {
+		if next := slug.ConsumeLivePort(rp); next <= 0 {
+			return
+		} else if _, found := tried[next]; !found {
+			port = next
+			return
+		}
+	}
+	return
+}
+
+// isOriginRetryable returns true if the failed origin request can be sent to
+// another worker; connection errors happen before the request is sent so any
+// request without a body is safe to retry, other errors are only retried for
+// idempotent requests without a body
+func isOriginRetryable(req *http.Request, err error) (retryable bool) {
+	if errors.Is(err, context.Canceled) || req.Context().Err() != nil {
+		return
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return
+	} else if isOriginDialError(err) {
+		retryable = true
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		retryable = true
+	}
+	return
+}
+
+// isOriginDialError returns true if the error is from connecting to the
+// worker, ie: the worker has crashed or is not yet listening
+func isOriginDialError(err error) (dial bool) {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		dial = true
+		return
+	}
+	dial = strings.Contains(err.Error(), "connection refused")
+	return
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsOriginDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen error: %v", err)
+	}
+	closedAddr := listener.Addr().String()
+	_ = listener.Close()
+	_, refusedErr := net.Dial("tcp", closedAddr)
+	_, missingErr := net.Dial("unix", filepath.Join(t.TempDir(), "missing.sock"))
+
+	tests := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{"connection refused", refusedErr, true},
+		{"missing socket", missingErr, true},
+		{"wrapped missing socket", &url.Error{Op: "Get", URL: "http://example.com/", Err: missingErr}, true},
+		{"worker not found", &net.OpError{Op: "dial", Net: "unix", Err: errors.New("worker not found")}, true},
+		{"read error", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}, false},
+		{"canceled", context.Canceled, false},
+		{"other", fmt.Errorf("origin missing this-slug: %v", "app"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.err == nil {
+				t.Fatalf("expected an error to test")
+			}
+			if got := isOriginDialError(test.err); got != test.expect {
+				t.Errorf("isOriginDialError(%v) = %v, expected %v", test.err, got, test.expect)
+			}
+		})
+	}
+}
+
+func TestSlugHttpClientDoMissingSocket(t *testing.T) {
+	app := &Application{
+		Name:   "test",
+		Origin: AppOrigin{Scheme: "http", Host: "127.0.0.1", Socket: true},
+		Config: &Config{},
+	}
+	slug := &Slug{App: app, Name: "test--0000000000", Workers: make(map[string]*SlugWorker)}
+	slug.Workers["0000000000"] = &SlugWorker{
+		Slug:       slug,
+		Hash:       "0000000000",
+		Port:       1,
+		SocketFile: filepath.Join(t.TempDir(), "missing.sock"),
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest error: %v", err)
+	}
+	response, err := slug.HttpClientDo(1, req)
+	if err == nil {
+		_ = response.Body.Close()
+		t.Fatalf("HttpClientDo expected an error for a missing socket")
+	}
+	if !isOriginDialError(err) {
+		t.Errorf("isOriginDialError(%v) = false, expected true", err)
+	}
+}
+
+func TestRetryBudgets(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratio   float64
+		minimum float64
+		idle    int
+		failing int
+		allowed int
+	}{
+		{"within minimum", 0.2, 10, 0, 5, 5},
+		{"minimum exhausted", 0, 3, 0, 5, 3},
+		{"ratio refills", 0.5, 1, 0, 4, 2},
+		{"whole ratio", 1, 1, 0, 5, 5},
+		{"idle requests capped at minimum", 0.25, 2, 20, 4, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rb := NewRetryBudgets()
+			for i := 0; i < test.idle; i++ {
+				rb.Deposit("app", test.ratio, test.minimum)
+			}
+			var allowed int
+			for i := 0; i < test.failing; i++ {
+				rb.Deposit("app", test.ratio, test.minimum)
+				if rb.Withdraw("app", test.minimum) {
+					allowed += 1
+				}
+			}
+			if allowed != test.allowed {
+				t.Errorf("allowed %d retries, expected %d", allowed, test.allowed)
+			}
+		})
+	}
+}
@@ -15,34 +15,53 @@
 package niseroku
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/go-enjin/be/pkg/net/serve"
 )
 
-func (rp *ReverseProxy) ServeOriginHTTP(app *Application, forwardFor string, w http.ResponseWriter, r *http.Request) (status int, err error) {
+// prepareOriginRequest clones the client request for sending to the app's
+// slug workers
+func (rp *ReverseProxy) prepareOriginRequest(ctx context.Context, app *Application, forwardFor string, r *http.Request) (req *http.Request) {
+	req = r.Clone(ctx)
+	req.Host = r.Host
+	req.URL.Host = r.Host
+	req.URL.Scheme = app.Origin.Scheme
+	req.RequestURI = ""
+	req.Header.Set("X-Proxy", "niseroku")
+	forwarded := rp.getForwarded(r)
+	forwarded.Addr = forwardFor
+	setForwardedHeaders(req, forwarded)
+	if app.Proxy.StripPrefix {
+		if _, route, ok := rp.GetAppRoute(r); ok && route.App == app {
+			route.StripPrefix(req)
+		}
+	}
+	return
+}
+
+func (rp *ReverseProxy) ServeOriginHTTP(app *Application, slugPort int, forwardFor string, w http.ResponseWriter, r *http.Request) (status int, err error) {
 
 	if app.Maintenance {
 		status = http.StatusServiceUnavailable
-		serve.Serve503(w, r)
+		rp.serveError(w, r, app, status)
 		return
 	}
 
-	req := r.Clone(r.Context())
-	req.Host = r.Host
-	req.URL.Host = r.Host
-	req.URL.Scheme = app.Origin.Scheme
-	req.RequestURI = ""
-	req.Header.Set("X-Proxy", "niseroku")
-	req.Header.Set("X-Forwarded-For", forwardFor)
+	// cancel the origin request when the client goes away or streaming fails
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	req := rp.prepareOriginRequest(ctx, app, forwardFor, r)
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &clientBodyReader{ReadCloser: req.Body}
+	}
 
 	var slug *Slug
-	var slugPort int
-	if slug = app.GetThisSlug(); slug == nil {
+	if slug = rp.getRequestSlug(app, r); slug == nil {
 		err = fmt.Errorf("origin missing this-slug: %v", app.Name)
 		return
 	} else {
@@ -52,7 +71,7 @@ func (rp *ReverseProxy) ServeOriginHTTP(app *Application, forwardFor string, w h
 		case !running && !ready:
 			for i := 0; i < 100; i++ {
 				time.Sleep(100 * time.Millisecond)
-				if slug = app.GetThisSlug(); slug != nil {
+				if slug = rp.getRequestSlug(app, r); slug != nil {
 					if running, ready = slug.IsRunningReady(); running && ready {
 						break
 					}
@@ -61,12 +80,12 @@ func (rp *ReverseProxy) ServeOriginHTTP(app *Application, forwardFor string, w h
 			if !running {
 				status = http.StatusBadGateway
 				rp.LogInfoF("origin not running and not ready: [502] %v\n", slug.Name)
-				serve.Serve502(w, r)
+				rp.serveError(w, r, app, status)
 				return
 			} else if !ready {
 				status = http.StatusServiceUnavailable
 				rp.LogInfoF("origin running and not ready: [503] %v\n", slug.Name)
-				serve.Serve503(w, r)
+				rp.serveError(w, r, app, status)
 				return
 			}
 		case !running && ready:
@@ -74,42 +93,95 @@ func (rp *ReverseProxy) ServeOriginHTTP(app *Application, forwardFor string, w h
 		case running && ready:
 		}
 
-		slugPort = slug.ConsumeLivePort()
+		if slugPort <= 0 || !slug.IsLivePort(slugPort) {
+			// this-slug changed since the port was selected
+			slugPort = slug.ConsumeLivePort(rp)
+		}
+	}
+
+	getAccessRecord(r).SetUpstream(slug, slugPort)
+
+	if IsUpgradeRequest(r) {
+		status, err = rp.ServeUpgradeHTTP(slug, slugPort, w, r, req)
+		return
 	}
 
 	var response *http.Response
 
-	if response, err = slug.HttpClientDo(slugPort, req); err != nil {
-		if strings.Contains(err.Error(), "connection reset by peer") {
+	budget := app.Proxy.RetryBudget
+	rp.retries.Deposit(app.Name, budget.GetRatio(), budget.GetMinimum())
+	tried := map[int]struct{}{slugPort: {}}
+	for attempt := 1; ; attempt++ {
+		response, err = slug.HttpClientDo(slugPort, req)
+		rp.recordOriginResult(app, slug, slugPort, req, err)
+		if err == nil {
+			break
+		} else if attempt >= app.Retry.GetAttempts() || !isOriginRetryable(req, err) {
+			return
+		}
+		nextPort := rp.nextRetryPort(slug, tried)
+		if nextPort <= 0 && isOriginDialError(err) {
+			// no other worker to try and this one is not listening
+			return
+		} else if !rp.retries.Withdraw(app.Name, budget.GetMinimum()) {
+			rp.LogInfoF("[retry] budget exhausted: %v - %v\n", app.Name, err)
+			return
+		}
+		if nextPort > 0 {
+			slugPort = nextPort
+			tried[slugPort] = struct{}{}
+			getAccessRecord(r).SetUpstream(slug, slugPort)
+		} else {
+			// only one worker, give it a moment before trying again
 			time.Sleep(100 * time.Millisecond)
-			if response, err = slug.HttpClientDo(slugPort, req); err != nil {
-				return
-			}
 		}
+		rp.metrics.Inc("niseroku_origin_retries_total", "app", app.Name)
+		rp.LogInfoF("[retry] attempt %d on port %d: %v - %v\n", attempt+1, slugPort, app.Name, err)
 	}
+	defer func() { _ = response.Body.Close() }()
 
 	for k, v := range response.Header {
+		if k == "Strict-Transport-Security" && w.Header().Get(k) != "" {
+			// the app.toml hsts policy takes precedence
+			continue
+		}
 		for _, vv := range v {
 			w.Header().Add(k, vv)
 		}
 	}
+	announced := announceTrailers(w, response)
+
+	var dst http.ResponseWriter = w
+	var cw *compressWriter
+	if encoding := rp.negotiateCompression(app, w, r, response); encoding != "" {
+		cw = newCompressWriter(w, encoding)
+		defer func() { _ = cw.Close() }()
+		dst = cw
+	}
 
 	status = response.StatusCode
 	w.WriteHeader(status)
-	// prevent 204 responses from having any body
+	// prevent 204 and 304 responses from having any body
 	if serve.StatusHasBody(status) {
-		var body []byte
-		if body, err = io.ReadAll(response.Body); err != nil {
-			rp.LogErrorF("error reading response.Body: %v -- %v", err, req)
-			status = http.StatusInternalServerError
-			serve.Serve500(w, r)
+		if _, err = streamResponseBody(dst, response.Body, getFlushInterval(response)); err != nil {
+			cancel()
+			if r.Context().Err() != nil {
+				// client disconnected, origin request is cancelled
+				err = context.Canceled
+				return
+			}
+			// headers are already sent, nothing more can be done for the client
+			rp.LogErrorF("error streaming response.Body: %v -- %v %v", err, req.Method, req.URL.String())
 			err = nil
 			return
 		}
-		_, err = w.Write(body)
 	}
-	if flusher, ok := w.(http.Flusher); ok {
-		flusher.Flush()
+	if cw != nil {
+		// the encoded body must be complete before any trailers
+		if ee := cw.Close(); ee != nil {
+			rp.LogErrorF("error closing %v encoder: %v -- %v %v", cw.encoding, ee, req.Method, req.URL.String())
+		}
 	}
+	copyTrailers(w, response, announced)
 	return
 }
@@ -0,0 +1,446 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	clpath "github.com/go-corelibs/path"
+)
+
+// statsLatencyBounds are the upper bounds of the latency histogram buckets,
+// percentiles are reported as the upper bound of the bucket they fall in
+var statsLatencyBounds = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+var statsStatusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// StatsCounters are the aggregates of one series within one stats bucket
+type StatsCounters struct {
+	Requests   int64
+	Status     [5]int64
+	Delayed    int64
+	Limited    int64
+	Latency    [16]int64
+	MaxLatency time.Duration
+}
+
+func (c *StatsCounters) observe(status int, latency time.Duration) {
+	c.Requests += 1
+	if class := status/100 - 1; class >= 0 && class < len(c.Status) {
+		c.Status[class] += 1
+	}
+	idx := len(statsLatencyBounds)
+	for i, bound := range statsLatencyBounds {
+		if latency <= bound {
+			idx = i
+			break
+		}
+	}
+	c.Latency[idx] += 1
+	if latency > c.MaxLatency {
+		c.MaxLatency = latency
+	}
+}
+
+func (c *StatsCounters) add(other *StatsCounters) {
+	c.Requests += other.Requests
+	for i := range c.Status {
+		c.Status[i] += other.Status[i]
+	}
+	c.Delayed += other.Delayed
+	c.Limited += other.Limited
+	for i := range c.Latency {
+		c.Latency[i] += other.Latency[i]
+	}
+	if other.MaxLatency > c.MaxLatency {
+		c.MaxLatency = other.MaxLatency
+	}
+}
+
+// Percentile returns the latency below which the given ratio of requests
+// completed
+func (c *StatsCounters) Percentile(ratio float64) (latency time.Duration) {
+	var observed int64
+	for _, count := range c.Latency {
+		observed += count
+	}
+	if observed == 0 {
+		return
+	}
+	rank := int64(ratio * float64(observed))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, count := range c.Latency {
+		if seen += count; seen >= rank {
+			if i < len(statsLatencyBounds) && statsLatencyBounds[i] < c.MaxLatency {
+				latency = statsLatencyBounds[i]
+			} else {
+				latency = c.MaxLatency
+			}
+			return
+		}
+	}
+	return
+}
+
+func (c *StatsCounters) Row(start time.Time) (row ProxyControlStatsRow) {
+	toMs := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000.0
+	}
+	row = ProxyControlStatsRow{
+		Time:     start,
+		Requests: c.Requests,
+		Status:   make(map[string]int64),
+		Delayed:  c.Delayed,
+		Limited:  c.Limited,
+		P50:      toMs(c.Percentile(0.50)),
+		P90:      toMs(c.Percentile(0.90)),
+		P99:      toMs(c.Percentile(0.99)),
+		Max:      toMs(c.MaxLatency),
+	}
+	for i, class := range statsStatusClasses {
+		row.Status[class] = c.Status[i]
+	}
+	return
+}
+
+// StatsBucket holds the aggregates of all series for one interval
+type StatsBucket struct {
+	Start  int64
+	Series map[string]*StatsCounters
+}
+
+// StatsRing is a fixed number of buckets of one interval width, reused as
+// time moves on
+type StatsRing struct {
+	Width   time.Duration
+	Buckets []StatsBucket
+
+	// maxAddrs limits the address series across all buckets, rings without
+	// a limit do not keep address series
+	maxAddrs int
+	addrs    int
+}
+
+func newStatsRing(width time.Duration, size, maxAddrs int) (r *StatsRing) {
+	r = &StatsRing{
+		Width:    width,
+		Buckets:  make([]StatsBucket, size),
+		maxAddrs: maxAddrs,
+	}
+	return
+}
+
+// countAddrs returns the number of address series in the bucket
+func (b *StatsBucket) countAddrs() (count int) {
+	for key := range b.Series {
+		if strings.HasPrefix(key, "addr|") {
+			count += 1
+		}
+	}
+	return
+}
+
+// keepsAddrs returns true if the ring keeps address series
+func (r *StatsRing) keepsAddrs() (keeps bool) {
+	keeps = r.maxAddrs > 0
+	return
+}
+
+// Span returns how far back the ring reaches
+func (r *StatsRing) Span() (span time.Duration) {
+	span = r.Width * time.Duration(len(r.Buckets))
+	return
+}
+
+// bucket returns the bucket for the given time, resetting the bucket if it
+// last held an older interval
+func (r *StatsRing) bucket(now time.Time) (b *StatsBucket) {
+	start := now.Truncate(r.Width).Unix()
+	idx := int((start / int64(r.Width.Seconds())) % int64(len(r.Buckets)))
+	if b = &r.Buckets[idx]; b.Start != start || b.Series == nil {
+		r.addrs -= b.countAddrs()
+		b.Start = start
+		b.Series = make(map[string]*StatsCounters)
+	}
+	return
+}
+
+// counters returns the counters of the series in the bucket for the given
+// time, or nil for address series if the ring does not keep them; addresses
+// beyond the ring's address limit share one series
+func (r *StatsRing) counters(now time.Time, key string) (c *StatsCounters) {
+	isAddr := strings.HasPrefix(key, "addr|")
+	if isAddr && !r.keepsAddrs() {
+		return
+	}
+	b := r.bucket(now)
+	var ok bool
+	if c, ok = b.Series[key]; ok {
+		return
+	} else if isAddr && r.addrs >= r.maxAddrs {
+		key = "addr|other"
+		if c, ok = b.Series[key]; ok {
+			return
+		}
+	}
+	c = new(StatsCounters)
+	b.Series[key] = c
+	if isAddr {
+		r.addrs += 1
+	}
+	return
+}
+
+// adopt takes the buckets of the loaded ring if it has the same width and
+// size, address series are dropped if this ring does not keep them
+func (r *StatsRing) adopt(loaded *StatsRing) {
+	if loaded == nil || loaded.Width != r.Width || len(loaded.Buckets) != len(r.Buckets) {
+		return
+	}
+	r.Buckets, r.addrs = loaded.Buckets, 0
+	for idx := range r.Buckets {
+		b := &r.Buckets[idx]
+		if !r.keepsAddrs() {
+			for key := range b.Series {
+				if strings.HasPrefix(key, "addr|") {
+					delete(b.Series, key)
+				}
+			}
+		}
+		r.addrs += b.countAddrs()
+	}
+}
+
+// lookup returns the counters of the series for the interval starting at the
+// given time, or nil if there were none
+func (r *StatsRing) lookup(start time.Time, key string) (c *StatsCounters) {
+	unix := start.Unix()
+	idx := int((unix / int64(r.Width.Seconds())) % int64(len(r.Buckets)))
+	if b := r.Buckets[idx]; b.Start == unix {
+		c = b.Series[key]
+	}
+	return
+}
+
+// statsSnapshot is the on-disk form of the StatsHistory
+type statsSnapshot struct {
+	Seconds *StatsRing
+	Minutes *StatsRing
+}
+
+// StatsHistory keeps per-second and per-minute aggregates of the requests,
+// status classes, latencies and rate limiting of the tracked series
+type StatsHistory struct {
+	seconds *StatsRing
+	minutes *StatsRing
+
+	sync.RWMutex
+}
+
+// NewStatsHistory returns an empty history sized by the stats settings, only
+// the per-second ring keeps remote address series
+func NewStatsHistory(config StatsConfig) (h *StatsHistory) {
+	h = &StatsHistory{
+		seconds: newStatsRing(time.Second, config.Seconds, config.MaxAddresses),
+		minutes: newStatsRing(time.Minute, config.Minutes, 0),
+	}
+	return
+}
+
+func (h *StatsHistory) update(keys []string, fn func(c *StatsCounters)) {
+	now := time.Now()
+	h.Lock()
+	defer h.Unlock()
+	for _, key := range keys {
+		for _, ring := range []*StatsRing{h.seconds, h.minutes} {
+			if c := ring.counters(now, key); c != nil {
+				fn(c)
+			}
+		}
+	}
+}
+
+// Request records a completed request for each of the series keys
+func (h *StatsHistory) Request(status int, latency time.Duration, keys ...string) {
+	h.update(keys, func(c *StatsCounters) {
+		c.observe(status, latency)
+	})
+}
+
+// Delayed records a request delayed by rate limiting
+func (h *StatsHistory) Delayed(keys ...string) {
+	h.update(keys, func(c *StatsCounters) {
+		c.Delayed += 1
+	})
+}
+
+// Limited records a request rejected by rate limiting
+func (h *StatsHistory) Limited(keys ...string) {
+	h.update(keys, func(c *StatsCounters) {
+		c.Limited += 1
+	})
+}
+
+// Query returns the rows of the series since the given duration ago, using
+// the per-second ring if it covers the whole duration unless the minute
+// resolution is requested
+func (h *StatsHistory) Query(key string, since time.Duration, resolution string) (stats ProxyControlStats, err error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var ring *StatsRing
+	isAddr := strings.HasPrefix(key, "addr|")
+	switch resolution {
+	case "second":
+		ring = h.seconds
+	case "minute":
+		ring = h.minutes
+	case "":
+		if ring = h.seconds; since > ring.Span() && !isAddr {
+			ring = h.minutes
+		}
+	default:
+		err = fmt.Errorf("unknown resolution: %q", resolution)
+		return
+	}
+	if isAddr && !ring.keepsAddrs() {
+		err = fmt.Errorf("remote address history is only kept per-second")
+		return
+	}
+	if since <= 0 || since > ring.Span() {
+		since = ring.Span()
+	}
+
+	now := time.Now()
+	first := now.Add(-since).Truncate(ring.Width).Add(ring.Width)
+	stats = ProxyControlStats{
+		Key:        key,
+		Resolution: CheckAB("second", "minute", ring == h.seconds),
+		Since:      first,
+		Rows:       []ProxyControlStatsRow{},
+	}
+
+	total := new(StatsCounters)
+	for start := first; !start.After(now); start = start.Add(ring.Width) {
+		c := ring.lookup(start, key)
+		if c == nil {
+			c = new(StatsCounters)
+		}
+		total.add(c)
+		stats.Rows = append(stats.Rows, c.Row(start))
+	}
+	stats.Total = total.Row(first)
+	return
+}
+
+// Save writes the history to the given file, replacing it atomically
+func (h *StatsHistory) Save(file string) (err error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	temp := file + ".tmp"
+	var fh *os.File
+	if fh, err = os.OpenFile(temp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660); err != nil {
+		return
+	}
+	zw := gzip.NewWriter(fh)
+	err = gob.NewEncoder(zw).Encode(statsSnapshot{Seconds: h.seconds, Minutes: h.minutes})
+	if ee := zw.Close(); err == nil {
+		err = ee
+	}
+	if ee := fh.Close(); err == nil {
+		err = ee
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return
+	}
+	err = os.Rename(temp, file)
+	return
+}
+
+// Load reads the history saved to the given file, rings with a different
+// width or size are discarded
+func (h *StatsHistory) Load(file string) (err error) {
+	if !clpath.IsFile(file) {
+		return
+	}
+	var fh *os.File
+	if fh, err = os.Open(file); err != nil {
+		return
+	}
+	defer func() { _ = fh.Close() }()
+	var zr *gzip.Reader
+	if zr, err = gzip.NewReader(fh); err != nil {
+		return
+	}
+	defer func() { _ = zr.Close() }()
+	var snapshot statsSnapshot
+	if err = gob.NewDecoder(zr).Decode(&snapshot); err != nil {
+		return
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	h.seconds.adopt(snapshot.Seconds)
+	h.minutes.adopt(snapshot.Minutes)
+	return
+}
+
+// controlStats returns the stats history of one series, the total of all
+// requests if no series key is given
+func (rp *ReverseProxy) controlStats(args ProxyControlStatsArgs) (stats ProxyControlStats, err error) {
+	since := DefaultStatsSince
+	if args.Since != "" {
+		if since, err = time.ParseDuration(args.Since); err != nil {
+			err = fmt.Errorf("invalid since duration: %v", err)
+			return
+		}
+	}
+	key := args.Key
+	if key == "" {
+		key = "__total__"
+	} else if key != "__total__" && !strings.HasPrefix(key, "app|") && !strings.HasPrefix(key, "host|") && !strings.HasPrefix(key, "addr|") {
+		err = fmt.Errorf("invalid stats key: %q", key)
+		return
+	}
+	stats, err = rp.stats.Query(key, since, args.Resolution)
+	return
+}
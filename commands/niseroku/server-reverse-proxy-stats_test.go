@@ -0,0 +1,97 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsCountersPercentile(t *testing.T) {
+	observe := func(latencies ...time.Duration) (c *StatsCounters) {
+		c = new(StatsCounters)
+		for _, latency := range latencies {
+			c.observe(200, latency)
+		}
+		return
+	}
+	repeat := func(count int, latency time.Duration) (latencies []time.Duration) {
+		for i := 0; i < count; i++ {
+			latencies = append(latencies, latency)
+		}
+		return
+	}
+
+	tests := []struct {
+		name     string
+		counters *StatsCounters
+		ratio    float64
+		expect   time.Duration
+	}{
+		{"empty", observe(), 0.5, 0},
+		{"single", observe(3 * time.Millisecond), 0.5, 3 * time.Millisecond},
+		{"single p99", observe(3 * time.Millisecond), 0.99, 3 * time.Millisecond},
+		{"zero ratio", observe(time.Millisecond, time.Second), 0, time.Millisecond},
+		{"bucket bound", observe(3*time.Millisecond, 40*time.Millisecond), 0.5, 5 * time.Millisecond},
+		{"max latency", observe(3*time.Millisecond, 40*time.Millisecond), 1, 40 * time.Millisecond},
+		{"p50", observe(append(repeat(50, time.Millisecond), repeat(50, 150*time.Millisecond)...)...), 0.5, time.Millisecond},
+		{"p90", observe(append(repeat(89, time.Millisecond), repeat(11, 150*time.Millisecond)...)...), 0.9, 150 * time.Millisecond},
+		{"p99", observe(append(repeat(99, time.Millisecond), 150*time.Millisecond)...), 0.99, time.Millisecond},
+		{"beyond bounds", observe(2*time.Minute, 3*time.Minute), 0.5, 3 * time.Minute},
+		{"merged", func() (c *StatsCounters) {
+			c = observe(repeat(10, 8*time.Millisecond)...)
+			c.add(observe(repeat(90, 800*time.Millisecond)...))
+			return
+		}(), 0.5, 800 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if latency := test.counters.Percentile(test.ratio); latency != test.expect {
+				t.Errorf("Percentile(%v) = %v, expected %v", test.ratio, latency, test.expect)
+			}
+		})
+	}
+}
+
+func TestStatsRingAddresses(t *testing.T) {
+	now := time.Now()
+	seconds := newStatsRing(time.Second, 10, 2)
+	minutes := newStatsRing(time.Minute, 10, 0)
+
+	for _, addr := range []string{"addr|a", "addr|b", "addr|c", "addr|d"} {
+		if c := seconds.counters(now, addr); c == nil {
+			t.Fatalf("seconds.counters(%q) = nil", addr)
+		} else {
+			c.Requests += 1
+		}
+		if c := minutes.counters(now, addr); c != nil {
+			t.Errorf("minutes.counters(%q) kept an address series", addr)
+		}
+	}
+	if c := seconds.lookup(now.Truncate(time.Second), "addr|other"); c == nil || c.Requests != 2 {
+		t.Errorf("addresses beyond the limit not shared: %+v", c)
+	}
+	if c := minutes.counters(now, "app|test"); c == nil {
+		t.Errorf("minutes.counters(app|test) = nil")
+	}
+
+	// reusing the bucket of an older interval releases its addresses
+	later := now.Add(10 * time.Second)
+	if c := seconds.counters(later, "addr|e"); c == nil {
+		t.Fatalf("seconds.counters(addr|e) = nil")
+	} else if seconds.lookup(later.Truncate(time.Second), "addr|e") != c {
+		t.Errorf("address not kept after its bucket was reused")
+	}
+}
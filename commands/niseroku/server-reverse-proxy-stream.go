@@ -0,0 +1,159 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// getFlushInterval returns the interval to use when streaming the given
+// response, a negative value indicates that every write is to be flushed
+func getFlushInterval(response *http.Response) (interval time.Duration) {
+	if mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type")); err == nil && mediaType == "text/event-stream" {
+		interval = -1
+	} else if response.ContentLength == -1 {
+		interval = -1
+	} else {
+		interval = DefaultProxyFlushInterval
+	}
+	return
+}
+
+// announceTrailers adds the response trailer keys to the "Trailer" header so
+// that they can be sent after the body is streamed
+func announceTrailers(w http.ResponseWriter, response *http.Response) (announced int) {
+	for key := range response.Trailer {
+		w.Header().Add("Trailer", key)
+		announced += 1
+	}
+	return
+}
+
+// copyTrailers copies the response trailer values to the client, any trailers
+// not announced before the body was streamed use the http.TrailerPrefix
+func copyTrailers(w http.ResponseWriter, response *http.Response, announced int) {
+	if len(response.Trailer) == 0 {
+		return
+	}
+	prefix := ""
+	if len(response.Trailer) != announced {
+		prefix = http.TrailerPrefix
+	}
+	for key, values := range response.Trailer {
+		for _, value := range values {
+			w.Header().Add(prefix+key, value)
+		}
+	}
+}
+
+// streamResponseBody copies the src to dst, flushing either on every write
+// when interval is negative or periodically otherwise
+func streamResponseBody(w http.ResponseWriter, src io.Reader, interval time.Duration) (written int64, err error) {
+	rc := http.NewResponseController(w)
+	var dst io.Writer = w
+
+	if interval != 0 {
+		fw := &flushWriter{w: w, rc: rc, latency: interval}
+		defer fw.stop()
+		dst = fw
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		nr, re := src.Read(buf)
+		if nr > 0 {
+			nw, we := dst.Write(buf[:nr])
+			if nw > 0 {
+				written += int64(nw)
+			}
+			if we != nil {
+				err = we
+				return
+			}
+			if nr != nw {
+				err = io.ErrShortWrite
+				return
+			}
+		}
+		if re != nil {
+			if !errors.Is(re, io.EOF) {
+				err = re
+			}
+			break
+		}
+	}
+	if ee := rc.Flush(); ee != nil && !errors.Is(ee, http.ErrNotSupported) && err == nil {
+		err = ee
+	}
+	return
+}
+
+type flushWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	latency time.Duration
+
+	t       *time.Timer
+	pending bool
+
+	sync.Mutex
+}
+
+func (f *flushWriter) Write(p []byte) (n int, err error) {
+	f.Lock()
+	defer f.Unlock()
+	if n, err = f.w.Write(p); err != nil {
+		return
+	}
+	if f.latency < 0 {
+		_ = f.rc.Flush()
+		return
+	}
+	if f.pending {
+		return
+	}
+	if f.t == nil {
+		f.t = time.AfterFunc(f.latency, f.delayedFlush)
+	} else {
+		f.t.Reset(f.latency)
+	}
+	f.pending = true
+	return
+}
+
+func (f *flushWriter) delayedFlush() {
+	f.Lock()
+	defer f.Unlock()
+	if !f.pending {
+		// stop() was called
+		return
+	}
+	_ = f.rc.Flush()
+	f.pending = false
+}
+
+func (f *flushWriter) stop() {
+	f.Lock()
+	defer f.Unlock()
+	f.pending = false
+	if f.t != nil {
+		f.t.Stop()
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IsUpgradeRequest returns true if the request has a "Connection: Upgrade"
+// header and names the protocol to upgrade to
+func IsUpgradeRequest(r *http.Request) (upgrade bool) {
+	if r.Header.Get("Upgrade") == "" {
+		return
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if upgrade = strings.EqualFold(strings.TrimSpace(token), "upgrade"); upgrade {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ServeUpgradeHTTP dials the slug port directly, relays the upgrade request
+// and if the origin switches protocols, hijacks the client connection and
+// splices the two connections together until either side closes or the
+// upgrade-idle timeout is reached
+func (rp *ReverseProxy) ServeUpgradeHTTP(slug *Slug, port int, w http.ResponseWriter, r, req *http.Request) (status int, err error) {
+
+	var originConn net.Conn
+	if originConn, err = slug.DialWorker(port); err != nil {
+		return
+	}
+	defer func() { _ = originConn.Close() }()
+
+	if err = req.Write(originConn); err != nil {
+		err = fmt.Errorf("error writing upgrade request: %v", err)
+		return
+	}
+
+	originReader := bufio.NewReader(originConn)
+	var response *http.Response
+	if response, err = http.ReadResponse(originReader, req); err != nil {
+		err = fmt.Errorf("error reading upgrade response: %v", err)
+		return
+	}
+
+	for k, v := range response.Header {
+		for _, vv := range v {
+			w.Header().Add(k, vv)
+		}
+	}
+
+	if status = response.StatusCode; status != http.StatusSwitchingProtocols {
+		// origin declined the upgrade, relay the response as-is
+		defer func() { _ = response.Body.Close() }()
+		w.WriteHeader(status)
+		if _, ee := streamResponseBody(w, response.Body, getFlushInterval(response)); ee != nil {
+			rp.LogErrorF("error streaming declined upgrade response: %v -- %v %v", ee, req.Method, req.URL.String())
+		}
+		return
+	}
+
+	var clientConn net.Conn
+	var clientBuf *bufio.ReadWriter
+	if clientConn, clientBuf, err = http.NewResponseController(w).Hijack(); err != nil {
+		err = fmt.Errorf("error hijacking client connection: %v", err)
+		return
+	}
+	defer func() { _ = clientConn.Close() }()
+
+	response.Header = w.Header()
+	response.Body = nil
+	if err = response.Write(clientBuf); err != nil {
+		err = fmt.Errorf("error writing switching protocols response: %v", err)
+		return
+	} else if err = clientBuf.Flush(); err != nil {
+		err = fmt.Errorf("error flushing switching protocols response: %v", err)
+		return
+	}
+
+	domain, _, _ := rp.GetAppDomain(r)
+	remoteAddr := rp.getForwarded(r).Addr
+	trackingKeys := []string{"__upgrade__", "upgrade|app|" + slug.App.Name, "upgrade|host|" + domain, "upgrade|addr|" + remoteAddr}
+	rp.tracking.Increment(trackingKeys...)
+	defer rp.deferDecTracking(trackingKeys...)
+
+	idle := slug.GetUpgradeIdleTimeout()
+	client := &idleTimeoutConn{Conn: clientConn, timeout: idle}
+	origin := &idleTimeoutConn{Conn: originConn, timeout: idle}
+
+	errs := make(chan error, 2)
+	splice := func(dst io.Writer, src io.Reader) {
+		_, ee := io.Copy(dst, src)
+		errs <- ee
+	}
+	// any bytes already buffered are relayed before the connections themselves
+	clientBuffered := io.LimitReader(clientBuf.Reader, int64(clientBuf.Reader.Buffered()))
+	originBuffered := io.LimitReader(originReader, int64(originReader.Buffered()))
+	go splice(origin, io.MultiReader(clientBuffered, client))
+	go splice(client, io.MultiReader(originBuffered, origin))
+
+	// the first side to finish closes both connections (deferred above)
+	if ee := <-errs; ee != nil && !isClosedConnError(ee) {
+		rp.LogInfoF("upgraded connection closed: %v - %v", slug.App.Name, ee)
+	}
+	return
+}
+
+func isClosedConnError(err error) (closed bool) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, net.ErrClosed), errors.Is(err, io.EOF):
+		closed = true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		closed = true
+	default:
+		closed = strings.Contains(err.Error(), "use of closed network connection")
+	}
+	return
+}
+
+// idleTimeoutConn extends the connection deadline on every read and write
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(p []byte) (n int, err error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	n, err = c.Conn.Read(p)
+	return
+}
+
+func (c *idleTimeoutConn) Write(p []byte) (n int, err error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	n, err = c.Conn.Write(p)
+	return
+}
@@ -16,6 +16,7 @@ package niseroku
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -47,10 +48,34 @@ type ReverseProxy struct {
 	https         *http.Server
 	httpsListener net.Listener
 	autocert      *autocert.Manager
+	certs         *StaticCerts
 
-	limiter *limiter.Limiter
+	errorPages *ErrorPages
+
+	auth *AuthFiles
+
+	cache *ResponseCache
+
+	limiter      *limiter.Limiter
+	appLimiters  map[string]*limiter.Limiter
+	limitersLock sync.RWMutex
 
 	tracking *Tracking
+	stats    *StatsHistory
+
+	metrics         *Metrics
+	metricsHttp     *http.Server
+	metricsListener net.Listener
+
+	health     *HealthChecks
+	healthStop chan struct{}
+
+	drains   *WorkerDrains
+	breakers *CircuitBreakers
+	retries  *RetryBudgets
+
+	canaries *Canaries
+	mirrors  *Mirrors
 
 	control net.Listener
 }
@@ -64,6 +89,19 @@ func NewReverseProxy(config *Config) (rp *ReverseProxy) {
 	rp.LogFile = config.LogFile
 	rp.config = config
 	rp.tracking = NewTracking()
+	rp.stats = NewStatsHistory(config.Stats)
+	rp.metrics = NewMetrics()
+	rp.health = NewHealthChecks()
+	rp.drains = NewWorkerDrains()
+	rp.breakers = NewCircuitBreakers()
+	rp.retries = NewRetryBudgets()
+	rp.canaries = NewCanaries()
+	rp.mirrors = NewMirrors()
+	rp.certs = NewStaticCerts()
+	rp.errorPages = NewErrorPages()
+	rp.auth = NewAuthFiles()
+	rp.cache = NewResponseCache()
+	rp.healthStop = make(chan struct{})
 	rp.BindFn = rp.Bind
 	rp.ServeFn = rp.Serve
 	rp.StopFn = rp.Stop
@@ -90,17 +128,21 @@ func (rp *ReverseProxy) Bind() (err error) {
 	rp.Lock()
 	defer rp.Unlock()
 
+	rp.reloadErrorPages()
+	rp.reloadAuthFiles()
+	rp.reloadResponseCache()
+	if ee := rp.stats.Load(rp.config.Paths.ProxyStats); ee != nil {
+		rp.LogErrorF("error loading stats history: %v\n", ee)
+	}
 	handler := rp.ProxyHttpHandler()
 	http.Handle("/", handler)
 
 	if rp.config.EnableSSL {
-		rp.autocert = &autocert.Manager{
-			Cache:      autocert.DirCache(rp.config.Paths.ProxySecrets),
-			Prompt:     autocert.AcceptTOS,
-			Email:      rp.config.AccountEmail,
-			HostPolicy: rp.autocertHostPolicy,
+		if rp.autocert, err = rp.newAutocertManager(); err != nil {
+			err = fmt.Errorf("error configuring acme: %v", err)
+			return
 		}
-		handler = rp.autocert.HTTPHandler(nil)
+		handler = rp.autocertHTTPHandler(rp.httpsRedirectHandler(handler))
 	}
 
 	httpAddr := fmt.Sprintf("%v:%d", rp.config.BindAddr, rp.config.Ports.Http)
@@ -108,17 +150,34 @@ func (rp *ReverseProxy) Bind() (err error) {
 		Addr:    httpAddr,
 		Handler: handler,
 	}
+	rp.config.Server.Apply(rp.http)
 	if rp.httpListener, err = net.Listen("tcp", httpAddr); err != nil {
 		return
+	} else if rp.config.Server.ProxyProtocol {
+		rp.httpListener = newProxyProtoListener(rp.httpListener, rp.acceptProxyProtocol)
 	}
 
 	if rp.config.EnableSSL {
+		rp.reloadStaticCerts()
+		tlsConfig := rp.autocert.TLSConfig()
+		tlsConfig.GetCertificate = rp.getCertificate
 		httpsAddr := fmt.Sprintf("%v:%d", rp.config.BindAddr, rp.config.Ports.Https)
 		rp.https = &http.Server{
 			Addr:      httpsAddr,
-			TLSConfig: rp.autocert.TLSConfig(),
+			TLSConfig: tlsConfig,
 		}
-		rp.httpsListener = rp.autocert.Listener()
+		rp.config.Server.Apply(rp.https)
+		var listener net.Listener
+		if listener, err = net.Listen("tcp", httpsAddr); err != nil {
+			return
+		} else if rp.config.Server.ProxyProtocol {
+			listener = newProxyProtoListener(listener, rp.acceptProxyProtocol)
+		}
+		rp.httpsListener = tls.NewListener(listener, tlsConfig)
+	}
+
+	if err = rp.bindMetrics(); err != nil {
+		return
 	}
 
 	go func() {
@@ -192,6 +251,19 @@ func (rp *ReverseProxy) Serve() (err error) {
 		wg.Done()
 	}()
 
+	if rp.metricsHttp != nil {
+		wg.Add(1)
+		go func() {
+			rp.LogInfoF("starting metrics service: %v\n", rp.config.Metrics.Listen)
+			if ee := rp.metricsServe(); ee != nil {
+				rp.LogErrorF("error running metrics service: %v\n", ee)
+			}
+			wg.Done()
+		}()
+	}
+
+	go rp.healthCheckServe()
+
 	rp.LogInfoF("all services running")
 	if wg.Wait(); err == nil {
 		rp.LogInfoF("awaiting idle connections")
@@ -217,6 +289,10 @@ func (rp *ReverseProxy) Serve() (err error) {
 func (rp *ReverseProxy) Stop() (err error) {
 	rp.Lock()
 	defer rp.Unlock()
+	if rp.healthStop != nil {
+		close(rp.healthStop)
+		rp.healthStop = nil
+	}
 	if rp.control != nil {
 		if ee := rp.control.Close(); ee != nil {
 			rp.LogErrorF("error closing control socket: %v\n", ee)
@@ -252,6 +328,18 @@ func (rp *ReverseProxy) Stop() (err error) {
 			rp.LogErrorF("panic caught https: %v", ee)
 		}
 	}
+	if rp.metricsHttp != nil {
+		if ee := rp.metricsHttp.Shutdown(context.Background()); ee != nil {
+			rp.LogErrorF("error shutting down metrics server: %v\n", ee)
+		} else {
+			rp.LogInfoF("metrics service shutdown")
+		}
+	}
+	if ee := rp.stats.Save(rp.config.Paths.ProxyStats); ee != nil {
+		rp.LogErrorF("error saving stats history: %v\n", ee)
+	} else {
+		rp.LogInfoF("stats history saved")
+	}
 	profiling.Stop()
 	return
 }
@@ -261,25 +349,82 @@ func (rp *ReverseProxy) Reload() (err error) {
 	defer rp.Unlock()
 	rp.LogInfoF("reverse-proxy reloading\n")
 	if err = rp.config.Reload(); err == nil {
-		rp.reloadRateLimiter()
-		if beIo.LogFile != rp.config.LogFile {
-			beIo.LogFile = rp.config.LogFile
-		}
+		rp.reloadServices()
 		for _, app := range rp.config.Applications {
-			if thisSlug := app.GetThisSlug(); thisSlug != nil {
-				thisSlug.RefreshWorkers()
-			} else {
-				rp.LogInfoF("this slug not found: %v", app.Name)
-			}
-			if nextSlug := app.GetNextSlug(); nextSlug != nil {
-				nextSlug.RefreshWorkers()
-			}
+			rp.refreshAppWorkers(app)
 		}
+		rp.pruneDrains()
+	}
+	return
+}
+
+// ReloadApp reloads only the named app's app.toml, the niseroku.toml settings
+// and other apps are left as they are and only the workers of the named app
+// are refreshed
+func (rp *ReverseProxy) ReloadApp(name string) (err error) {
+	rp.Lock()
+	defer rp.Unlock()
+	rp.LogInfoF("reverse-proxy reloading app: %v\n", name)
+	var app *Application
+	if app, err = rp.config.ReloadApplication(name); err != nil {
+		err = fmt.Errorf("error loading %v app.toml: %v", name, err)
+		return
 	}
+	rp.reloadServices()
+	rp.refreshAppWorkers(app)
+	rp.pruneDrains()
 	return
 }
 
+// reloadServices updates all reverse-proxy state derived from the config,
+// must be called after the config is reloaded
+func (rp *ReverseProxy) reloadServices() {
+	rp.metrics.Inc("niseroku_proxy_reloads_total")
+	rp.reloadRateLimiter()
+	rp.reloadErrorPages()
+	rp.reloadAuthFiles()
+	rp.reloadResponseCache()
+	rp.reloadMirrors()
+	if rp.config.EnableSSL {
+		rp.reloadStaticCerts()
+	}
+	if beIo.LogFile != rp.config.LogFile {
+		beIo.LogFile = rp.config.LogFile
+	}
+}
+
+func (rp *ReverseProxy) refreshAppWorkers(app *Application) {
+	if thisSlug := app.GetThisSlug(); thisSlug != nil {
+		thisSlug.RefreshWorkers()
+	} else {
+		rp.LogInfoF("this slug not found: %v", app.Name)
+	}
+	nextSlug := app.GetNextSlug()
+	if nextSlug != nil {
+		nextSlug.RefreshWorkers()
+	}
+	if shadow := app.Mirror.GetShadowSlug(app); shadow != nil && shadow != nextSlug {
+		shadow.RefreshWorkers()
+	}
+}
+
 func (rp *ReverseProxy) GetAppDomain(r *http.Request) (domain string, app *Application, ok bool) {
+	var route *AppRoute
+	if domain, route, ok = rp.GetAppRoute(r); ok {
+		app = route.App
+		return
+	}
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	if _, isRedirect := rp.config.RedirectLookup[domain]; isRedirect {
+		app, ok = rp.config.DomainLookup[domain]
+	}
+	return
+}
+
+// GetAppRoute returns the request domain and the longest path prefix route
+// matching the request
+func (rp *ReverseProxy) GetAppRoute(r *http.Request) (domain string, route *AppRoute, ok bool) {
 	if strings.Contains(r.Host, ":") {
 		if h, p, err := net.SplitHostPort(r.Host); err == nil {
 			switch {
@@ -294,9 +439,19 @@ func (rp *ReverseProxy) GetAppDomain(r *http.Request) (domain string, app *Appli
 	} else {
 		domain = r.Host
 	}
-	rp.RLock()
-	defer rp.RUnlock()
-	app, ok = rp.config.DomainLookup[domain]
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	route = findAppRoute(rp.config.RouteLookup[domain], r.URL.Path)
+	ok = route != nil
+	return
+}
+
+// GetDomainRedirect returns the [[redirects]] rule for the given domain, if
+// the domain is a redirect-only host
+func (rp *ReverseProxy) GetDomainRedirect(domain string) (redirect *AppRedirect) {
+	rp.config.RLock()
+	defer rp.config.RUnlock()
+	redirect = rp.config.RedirectLookup[domain]
 	return
 }
 
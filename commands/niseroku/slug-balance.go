@@ -0,0 +1,147 @@
+// Copyright (c) 2023  The Go-Enjin Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package niseroku
+
+import (
+	"math/rand"
+)
+
+// PortSelector provides the live port selection criteria
+type PortSelector interface {
+	// PortInFlight returns the number of in-flight requests for the given port
+	PortInFlight(port int) (inFlight int64)
+	// PortIsHealthy returns false if the given port is failing health checks
+	PortIsHealthy(port int) (healthy bool)
+	// PortIsDraining returns true if the given port is not to receive new
+	// requests
+	PortIsDraining(port int) (draining bool)
+	// PortCircuitOpen returns true if the given port has failed too many
+	// origin requests recently
+	PortCircuitOpen(port int) (open bool)
+}
+
+// GetLiveWorkers returns all live workers with ports, in live-hash order
+func (s *Slug) GetLiveWorkers() (workers []*SlugWorker) {
+	s.RLock()
+	defer s.RUnlock()
+	s.liveHashLock.RLock()
+	defer s.liveHashLock.RUnlock()
+	for _, hash := range s.Settings.Live {
+		if worker, ok := s.Workers[hash]; ok && worker.Port > 0 {
+			workers = append(workers, worker)
+		}
+	}
+	return
+}
+
+// GetLivePorts returns the ports of all live workers, in live-hash order
+func (s *Slug) GetLivePorts() (ports []int) {
+	for _, worker := range s.GetLiveWorkers() {
+		ports = append(ports, worker.Port)
+	}
+	return
+}
+
+// IsLivePort returns true if the given port belongs to a live worker
+func (s *Slug) IsLivePort(port int) (live bool) {
+	for _, livePort := range s.GetLivePorts() {
+		if live = livePort == port; live {
+			return
+		}
+	}
+	return
+}
+
+// getAvailableLivePorts returns the live ports without an open circuit
+// breaker, not failing health checks and not draining, or all live ports if
+// none of them are available
+func (s *Slug) getAvailableLivePorts(selector PortSelector) (ports []int) {
+	live := s.GetLivePorts()
+	for _, port := range live {
+		if selector != nil && (selector.PortCircuitOpen(port) || !selector.PortIsHealthy(port) || selector.PortIsDraining(port)) {
+			continue
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) == 0 {
+		ports = live
+	}
+	return
+}
+
+// ConsumeLivePort selects the next live port to proxy a request to, using the
+// application's proxy.balance strategy and the port selector given
+func (s *Slug) ConsumeLivePort(selector PortSelector) (consumedPort int) {
+	consumedPort = -1
+	ports := s.getAvailableLivePorts(selector)
+	numPorts := len(ports)
+	if numPorts == 0 {
+		s.App.LogErrorF("slug workers not found, no live ports to give: %v", s.Name)
+		return
+	}
+
+	s.liveHashLock.Lock()
+	defer s.liveHashLock.Unlock()
+
+	if s.liveHash < 0 || s.liveHash >= numPorts {
+		s.liveHash = 0
+	}
+	offset := s.liveHash
+	s.liveHash = (s.liveHash + 1) % numPorts
+
+	getLoad := func(port int) (inFlight int64) {
+		if selector != nil {
+			if inFlight = selector.PortInFlight(port); inFlight < 0 {
+				inFlight = 0
+			}
+		}
+		return
+	}
+
+	switch s.App.Proxy.GetBalance() {
+
+	case BalanceLeastConn:
+		// start at the round-robin offset so that ties are distributed
+		var least int64 = -1
+		for idx := 0; idx < numPorts; idx++ {
+			port := ports[(offset+idx)%numPorts]
+			if inFlight := getLoad(port); least < 0 || inFlight < least {
+				least = inFlight
+				consumedPort = port
+			}
+		}
+
+	case BalanceRandomTwo:
+		if numPorts == 1 {
+			consumedPort = ports[0]
+			break
+		}
+		first := rand.Intn(numPorts)
+		second := rand.Intn(numPorts - 1)
+		if second >= first {
+			second += 1
+		}
+		if getLoad(ports[second]) < getLoad(ports[first]) {
+			consumedPort = ports[second]
+		} else {
+			consumedPort = ports[first]
+		}
+
+	default:
+		consumedPort = ports[offset]
+	}
+
+	return
+}
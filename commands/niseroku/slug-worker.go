@@ -16,7 +16,9 @@ package niseroku
 
 import (
 	"fmt"
+	"hash/crc32"
 	"math/rand"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -43,10 +45,11 @@ type SlugWorker struct {
 	Pid  int `toml:"-"`
 	Port int `toml:"port"`
 
-	RunPath  string `toml:"run-path"`
-	PidFile  string `toml:"pid-file"`
-	PortFile string `toml:"port-file"`
-	LogFile  string `toml:"log-file"`
+	RunPath    string `toml:"run-path"`
+	PidFile    string `toml:"pid-file"`
+	PortFile   string `toml:"port-file"`
+	SocketFile string `toml:"socket-file"`
+	LogFile    string `toml:"log-file"`
 
 	sync.RWMutex
 }
@@ -67,6 +70,7 @@ func NewSlugWorkerWithHash(slug *Slug, hash string) (si *SlugWorker, err error)
 	si.RunPath = filepath.Join(slug.App.Config.Paths.TmpRun, si.Name)
 	si.PidFile = filepath.Join(slug.App.Config.Paths.TmpRun, si.Name+".pid")
 	si.PortFile = filepath.Join(slug.App.Config.Paths.TmpRun, si.Name+".port")
+	si.SocketFile = filepath.Join(slug.App.Config.Paths.TmpRun, si.Name+".sock")
 	si.LogFile = filepath.Join(slug.App.Config.Paths.VarLogs, slug.App.Name+".log")
 	_, _ = si.GetPid()
 	if clpath.IsFile(si.PortFile) {
@@ -172,6 +176,37 @@ func (s *SlugWorker) IsRunningReady() (running, ready bool) {
 	return
 }
 
+// IsListening returns true if the worker accepts connections on its port
+// within the given timeout, or when the app origin uses sockets, if the
+// worker's unix socket appears within the given timeout
+func (s *SlugWorker) IsListening(timeout time.Duration) (listening bool) {
+	if !s.Slug.App.Origin.Socket {
+		listening = common.IsAddressPortOpenWithTimeout(s.Slug.App.Origin.Host, s.Port, timeout)
+		return
+	}
+	for start := time.Now(); ; time.Sleep(50 * time.Millisecond) {
+		if info, err := os.Stat(s.SocketFile); err == nil {
+			if listening = info.Mode()&os.ModeSocket != 0; listening {
+				return
+			}
+		}
+		if time.Since(start) >= timeout {
+			return
+		}
+	}
+}
+
+// Dial connects to the worker's port, or to the worker's unix socket when the
+// app origin uses sockets
+func (s *SlugWorker) Dial() (conn net.Conn, err error) {
+	if s.Slug.App.Origin.Socket {
+		conn, err = net.Dial("unix", s.SocketFile)
+		return
+	}
+	conn, err = s.Slug.App.Origin.Dial(s.Port)
+	return
+}
+
 func (s *SlugWorker) GetBinProcess() (proc *process.Process, err error) {
 	if s.Pid > 0 {
 		proc, err = common.GetProcessFromPid(s.Pid)
@@ -181,8 +216,25 @@ func (s *SlugWorker) GetBinProcess() (proc *process.Process, err error) {
 	return
 }
 
+// socketWorkerKey returns the number identifying a socket worker in place of
+// a port, derived from the worker hash and above the range of tcp ports
+func socketWorkerKey(hash string) (key int) {
+	key = 65536 + int(crc32.ChecksumIEEE([]byte(hash))&0x3fffffff)
+	return
+}
+
+// ReserveUnusedPort returns a port within the app-start to app-end range which
+// is neither used by another worker nor open, socket workers do not listen on
+// a port and are given their socketWorkerKey instead
 func (s *SlugWorker) ReserveUnusedPort() (port int) {
 	lookup := s.Slug.App.Config.GetAllRunningPorts()
+	if s.Slug.App.Origin.Socket {
+		for port = socketWorkerKey(s.Hash); ; port += 1 {
+			if _, exists := lookup[port]; !exists {
+				return
+			}
+		}
+	}
 	rand.New(rand.NewSource(time.Now().UnixMicro()))
 	delta := s.Slug.App.Config.Ports.AppEnd - s.Slug.App.Config.Ports.AppStart
 	for loop := delta; loop > 0; loop -= 1 {
@@ -213,7 +265,17 @@ func (s *SlugWorker) PrepareStart(port int) (webCmd string, webArgv, environ []s
 		return
 	}
 
-	if common.IsAddressPortOpen(s.Slug.App.Origin.Host, port) {
+	if s.Slug.App.Origin.Socket {
+		if len(s.SocketFile) > MaxUnixSocketPathLength {
+			err = fmt.Errorf("socket path too long: %v", s.SocketFile)
+			return
+		}
+		// a stale socket would be mistaken for this worker being ready
+		if ee := os.Remove(s.SocketFile); ee != nil && !os.IsNotExist(ee) {
+			err = fmt.Errorf("error removing stale socket: %v - %v", s.SocketFile, ee)
+			return
+		}
+	} else if common.IsAddressPortOpen(s.Slug.App.Origin.Host, port) {
 		err = fmt.Errorf("port already open by another process")
 		s.Slug.App.LogErrorF("%v: %d\n", err, port)
 		return
@@ -234,10 +296,14 @@ func (s *SlugWorker) PrepareStart(port int) (webCmd string, webArgv, environ []s
 		}
 	}
 
-	s.Slug.App.LogInfoF("preparing slug instance: PORT=%d %v (%v)\n", port, web, s.Slug.Name)
-
 	env := s.Slug.App.OsEnviron()
-	env.Set("PORT", strconv.Itoa(port))
+	if s.Slug.App.Origin.Socket {
+		env.Set("SOCKET", s.SocketFile)
+		s.Slug.App.LogInfoF("preparing slug instance: SOCKET=%v %v (%v)\n", s.SocketFile, web, s.Slug.Name)
+	} else {
+		env.Set("PORT", strconv.Itoa(port))
+		s.Slug.App.LogInfoF("preparing slug instance: PORT=%d %v (%v)\n", port, web, s.Slug.Name)
+	}
 	environ = env.Environ()
 
 	var parsedArgs []string
@@ -388,4 +454,11 @@ func (s *SlugWorker) Cleanup() {
 			s.Slug.App.LogInfoF("removed slug port file: %v\n", s.PortFile)
 		}
 	}
+	if clpath.Exists(s.SocketFile) {
+		if err := os.Remove(s.SocketFile); err != nil {
+			s.Slug.App.LogErrorF("error removing slug socket file: %v - %v\n", s.SocketFile, err)
+		} else {
+			s.Slug.App.LogInfoF("removed slug socket file: %v\n", s.SocketFile)
+		}
+	}
 }
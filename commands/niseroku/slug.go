@@ -30,8 +30,6 @@ import (
 	"github.com/go-corelibs/maps"
 	clpath "github.com/go-corelibs/path"
 	"github.com/go-corelibs/slices"
-
-	"github.com/go-enjin/enjenv/pkg/service/common"
 )
 
 type Slug struct {
@@ -183,6 +181,18 @@ func (s *Slug) GetReadyIntervalTimeout() (timeout time.Duration) {
 	return
 }
 
+func (s *Slug) GetUpgradeIdleTimeout() (timeout time.Duration) {
+	switch {
+	case s.App.Timeouts.UpgradeIdle != nil:
+		timeout = *s.App.Timeouts.UpgradeIdle
+	case s.App.Config.Timeouts.UpgradeIdle > 0:
+		timeout = s.App.Config.Timeouts.UpgradeIdle
+	default:
+		timeout = DefaultUpgradeIdleTimeout
+	}
+	return
+}
+
 func (s *Slug) GetSlugWorkerHashes() (workers []string) {
 	s.RLock()
 	defer s.RUnlock()
@@ -199,40 +209,6 @@ func (s *Slug) GetNumWorkers() (num int) {
 	return
 }
 
-func (s *Slug) GetLivePort() (livePort int) {
-	s.liveHashLock.RLock()
-	defer s.liveHashLock.RUnlock()
-	livePort = -1
-	var hash string
-	if liveCount := len(s.Settings.Live); s.liveHash >= 0 && s.liveHash < liveCount {
-		hash = s.Settings.Live[s.liveHash]
-	} else if liveCount > 0 {
-		s.liveHash = 0
-		hash = s.Settings.Live[0]
-	} else {
-		// no slug instances
-		s.App.LogErrorF("slug workers not found, no live ports to give: %v", s.Name)
-		return
-	}
-	if worker, ok := s.Workers[hash]; ok {
-		livePort = worker.Port
-	} else {
-		s.App.LogErrorF("slug worker by live hash not found: %v - %v", hash, s.Name)
-	}
-	return
-}
-
-func (s *Slug) ConsumeLivePort() (consumedPort int) {
-	consumedPort = s.GetLivePort()
-	s.liveHashLock.Lock()
-	defer s.liveHashLock.Unlock()
-	s.liveHash += 1
-	if liveCount := len(s.Settings.Live); s.liveHash < 0 || s.liveHash > liveCount {
-		s.liveHash = 0
-	}
-	return
-}
-
 func (s *Slug) GetInstanceByPid(pid int) (si *SlugWorker) {
 	s.RLock()
 	defer s.RUnlock()
@@ -395,7 +371,7 @@ func (s *Slug) StartForegroundWorkers(workersReady chan bool) (err error) {
 		go func() {
 			s.App.LogInfoF("polling slug startup: %v - %v\n", s.Name, slugStartupTimeout)
 			for now := time.Now(); now.Sub(start) < slugStartupTimeout; now = time.Now() {
-				if common.IsAddressPortOpenWithTimeout(s.App.Origin.Host, reservedPort, readyIntervalTimeout) {
+				if si.IsListening(readyIntervalTimeout) {
 					if numReady += 1; numReady >= s.App.GetWebWorkers() {
 						s.liveHashLock.Lock()
 						for _, hash := range s.Settings.Live {
@@ -446,10 +422,36 @@ func (s *Slug) StartForegroundWorkers(workersReady chan bool) (err error) {
 	return
 }
 
+// DialWorker connects to the worker identified by the given port, using the
+// worker's unix socket when the app origin uses sockets
+func (s *Slug) DialWorker(port int) (conn net.Conn, err error) {
+	if !s.App.Origin.Socket {
+		conn, err = s.App.Origin.Dial(port)
+		return
+	}
+	var worker *SlugWorker
+	s.RLock()
+	for _, si := range s.Workers {
+		if si.Port == port {
+			worker = si
+			break
+		}
+	}
+	s.RUnlock()
+	if worker == nil {
+		// reported as a dial error so the request is retried on another worker
+		err = &net.OpError{Op: "dial", Net: "unix", Err: fmt.Errorf("worker not found for port %d: %v", port, s.Name)}
+		return
+	}
+	conn, err = worker.Dial()
+	return
+}
+
 func (s *Slug) HttpClientDo(port int, req *http.Request) (response *http.Response, err error) {
 	timeout := s.GetOriginRequestTimeout()
 	client := &http.Client{
-		Timeout: timeout,
+		// no overall client timeout, response bodies are streamed and may be
+		// long-lived (downloads, server-sent events and so on)
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -462,7 +464,7 @@ func (s *Slug) HttpClientDo(port int, req *http.Request) (response *http.Respons
 			ExpectContinueTimeout: timeout,
 			TLSHandshakeTimeout:   timeout,
 			DialContext: func(ctx context.Context, network string, addr string) (conn net.Conn, err error) {
-				conn, err = s.App.Origin.Dial(port)
+				conn, err = s.DialWorker(port)
 				return
 			},
 		},
@@ -440,22 +440,25 @@ func (sw *StatusWatch) update() {
 	sw.display.RequestShow()
 }
 
-func (sw *StatusWatch) gather() (snapshot *WatchSnapshot, proxyLimits string, err error) {
+func (sw *StatusWatch) gather() (snapshot *WatchSnapshot, proxyLimits, healthChecks string, err error) {
 	if err = sw.cliCmd.config.Reload(); err != nil {
 		return
 	}
 	if response, ee := sw.cliCmd.config.CallProxyControlCommand("proxy-limits"); ee == nil {
 		proxyLimits = response
 	}
+	if response, ee := sw.cliCmd.config.CallProxyControlCommand("health-checks"); ee == nil {
+		healthChecks = response
+	}
 	snapshot = sw.watching.Snapshot()
 	return
 }
 
 func (sw *StatusWatch) refresh() {
-	if snapshot, proxyLimits, err := sw.gather(); err != nil {
+	if snapshot, proxyLimits, healthChecks, err := sw.gather(); err != nil {
 		sw.showError(fmt.Sprintf("error gathering watching data: %v", err))
 	} else {
-		sw.refreshWatching(snapshot, proxyLimits)
+		sw.refreshWatching(snapshot, proxyLimits, healthChecks)
 	}
 }
 
@@ -517,9 +520,10 @@ func tDim(s string, n int) (out string) {
 	return
 }
 
-func (sw *StatusWatch) refreshWatching(snapshot *WatchSnapshot, proxyLimits string) {
+func (sw *StatusWatch) refreshWatching(snapshot *WatchSnapshot, proxyLimits, healthChecks string) {
 	stats := &snapshot.Stats
 	ppl := parseProxyLimits(proxyLimits)
+	phc := parseHealthChecks(healthChecks)
 
 	var cpuUsage float32 = 0.0
 	if len(stats.CpuUsage) > 0 {
@@ -624,7 +628,11 @@ func (sw *StatusWatch) refreshWatching(snapshot *WatchSnapshot, proxyLimits stri
 				if idx > 0 {
 					ports += ","
 				}
-				ports += strconv.Itoa(port)
+				if hc, ok := phc[port]; ok && !hc.Healthy {
+					ports += `<span foreground="white" background="red">` + strconv.Itoa(port) + `</span>`
+				} else {
+					ports += strconv.Itoa(port)
+				}
 			}
 		}
 		if slices.Present(stat.Name, "reverse-proxy", "git-repository") {
@@ -855,7 +863,7 @@ func (sw *StatusWatch) refreshWatching(snapshot *WatchSnapshot, proxyLimits stri
 	if numAddrs := len(ppl.Request.Addrs); numAddrs > 0 {
 		buf = bytes.NewBuffer([]byte(""))
 		tw = tabwriter.NewWriter(io.Writer(buf), 8, 2, 2, ' ', tabwriter.FilterHTML)
-		_, _ = tw.Write([]byte("REMOTE\tREQUESTS\tDELAYED\n"))
+		_, _ = tw.Write([]byte("REMOTE\tREQUESTS\tDELAYED\tUPGRADED\n"))
 		max := sw.cliCmd.config.ProxyLimit.Max
 		for _, key := range maps.SortedKeys(ppl.Request.Addrs) {
 			requests := ppl.Request.Addrs[key]
@@ -867,6 +875,12 @@ func (sw *StatusWatch) refreshWatching(snapshot *WatchSnapshot, proxyLimits stri
 			} else {
 				line += formatPercNumber(0, max)
 			}
+			line += "\t"
+			if upgraded, ok := ppl.Upgraded.Addrs[key]; ok {
+				line += formatPercNumber(upgraded, max)
+			} else {
+				line += formatPercNumber(0, max)
+			}
 			line += "\n"
 			_, _ = tw.Write([]byte(line))
 		}
@@ -16,6 +16,7 @@ package niseroku
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/go-corelibs/maps"
@@ -61,6 +62,20 @@ func (t *Tracking) String() (summary string) {
 	return
 }
 
+// Prefixed returns the values of all keys with the given prefix, with the
+// prefix removed from the returned keys
+func (t *Tracking) Prefixed(prefix string) (values map[string]int64) {
+	t.RLock()
+	defer t.RUnlock()
+	values = make(map[string]int64)
+	for key, value := range t.data {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			values[name] = value
+		}
+	}
+	return
+}
+
 func (t *Tracking) Increment(keys ...string) {
 	t.Lock()
 	defer t.Unlock()
@@ -174,10 +174,10 @@ func (w *Watching) updateSnapshot() {
 	if w.config.EnableSSL {
 		proxyPorts = append(proxyPorts, w.config.Ports.Https)
 	}
-	w.updateSnapshotEntry(&w.snapshot.Services[0], w.config.Paths.ProxyPidFile, proxyPorts)
+	w.updateSnapshotEntry(&w.snapshot.Services[0], w.config.Paths.ProxyPidFile, proxyPorts, nil)
 
 	// git-repository
-	w.updateSnapshotEntry(&w.snapshot.Services[1], w.config.Paths.RepoPidFile, []int{w.config.Ports.Git})
+	w.updateSnapshotEntry(&w.snapshot.Services[1], w.config.Paths.RepoPidFile, []int{w.config.Ports.Git}, nil)
 
 	// applications
 	for _, app := range w.config.Applications {
@@ -216,7 +216,13 @@ func (w *Watching) updateSnapshotApplication(app *Application) (stats []WatchPro
 					Num:     0,
 					Threads: 0,
 				}
-				w.updateSnapshotEntry(&stat, si.PidFile, []int{si.Port})
+				var listening func(port int) (ok bool)
+				if app.Origin.Socket {
+					listening = func(port int) (ok bool) {
+						return si.IsListening(100 * time.Millisecond)
+					}
+				}
+				w.updateSnapshotEntry(&stat, si.PidFile, []int{si.Port}, listening)
 				stats = append(stats, stat)
 			}
 		}
@@ -235,7 +241,13 @@ func (w *Watching) updateSnapshotApplication(app *Application) (stats []WatchPro
 	return
 }
 
-func (w *Watching) updateSnapshotEntry(entry *WatchProc, pidfile string, ports []int) {
+func (w *Watching) updateSnapshotEntry(entry *WatchProc, pidfile string, ports []int, listening func(port int) (ok bool)) {
+	if listening == nil {
+		listening = func(port int) (ok bool) {
+			return common.IsAddressPortOpen(w.config.BindAddr, port)
+		}
+	}
+
 	if clpath.IsFile(pidfile) {
 		var portsReady []int
 		var isRunning, isReady bool
@@ -260,7 +272,7 @@ func (w *Watching) updateSnapshotEntry(entry *WatchProc, pidfile string, ports [
 		}
 
 		for _, port := range ports {
-			if isReady = common.IsAddressPortOpen(w.config.BindAddr, port); isReady {
+			if isReady = listening(port); isReady {
 				portsReady = append(portsReady, port)
 			}
 		}
@@ -1,6 +1,8 @@
 module github.com/go-enjin/enjenv
 
-go 1.21.6
+// go 1.22 is the minimum required by github.com/klauspost/compress v1.18.0,
+// which provides the zstd encoder for reverse-proxy response compression
+go 1.22
 
 require (
 	github.com/BurntSushi/toml v1.3.2
@@ -25,6 +27,7 @@ require (
 	github.com/iancoleman/strcase v0.3.0
 	github.com/kataras/requestid v0.0.2
 	github.com/kevinburke/ssh_config v1.2.0
+	github.com/klauspost/compress v1.18.0
 	github.com/knqyf263/go-deb-version v0.0.0-20230223133812-3ed183d23422
 	github.com/otiai10/copy v1.14.0
 	github.com/pkg/profile v1.7.0